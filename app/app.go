@@ -19,6 +19,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"io"
@@ -94,61 +95,146 @@ func ExecSqlFile(path string) func(connection db.Connection) error {
 	}
 }
 
+// Step names the stage of an init or patch run that failed.
+type Step string
+
+const (
+	StepCheck      Step = "check"
+	StepBegin      Step = "begin"
+	StepExecute    Step = "execute"
+	StepCommit     Step = "commit"
+	StepRegister   Step = "register"
+	StepUnregister Step = "unregister"
+)
+
+// StepError is returned by InitContext, PatchContext and Rollback. It identifies the app, the patch
+// (empty for init), the step that failed and, for StepExecute, the index of the failing function.
+// Rollback is set if the error occurred while reverting the patch.
+type StepError struct {
+	App           string
+	Patch         string
+	Rollback      bool
+	Step          Step
+	FunctionIndex int
+	Err           error
+}
+
+func (e *StepError) Error() string {
+	target := fmt.Sprintf("init app %s", e.App)
+	if e.Patch != "" {
+		target = fmt.Sprintf("patch %s app %s", e.Patch, e.App)
+	}
+	if e.Rollback {
+		target = fmt.Sprintf("roll back patch %s app %s", e.Patch, e.App)
+	}
+	switch e.Step {
+	case StepCheck:
+		return fmt.Sprintf("cannot check state to %s: %v", target, e.Err)
+	case StepBegin:
+		return fmt.Sprintf("cannot start transaction to %s: %v", target, e.Err)
+	case StepExecute:
+		return fmt.Sprintf("cannot execute function %d to %s: %v", e.FunctionIndex, target, e.Err)
+	case StepCommit:
+		return fmt.Sprintf("cannot commit %s: %v", target, e.Err)
+	case StepRegister:
+		return fmt.Sprintf("cannot register %s: %v", target, e.Err)
+	case StepUnregister:
+		return fmt.Sprintf("cannot unregister patch %s app %s: %v", e.Patch, e.App, e.Err)
+	}
+	return fmt.Sprintf("cannot %s %s: %v", e.Step, target, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
 // The Init function must be used to run all the elements required for the app initialization process.
 // This function guarantees that everything will only run once when the app is first launched.
 // Furthermore, this function guarantees that either all database changes or no changes are committed using
 // transactions. For this you must use the connection that is passed to the function parameter.
+// Init exits the process on failure; use InitContext to handle errors yourself.
+// If several replicas of the app start at once, only one runs the init while the others wait up to
+// DefaultLockTimeout and skip it afterward.
 func Init(apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, initFunctions ...func(connection db.Connection) error) {
-	if appRegistered(apiEndpoint, apiKey, appAndSchemaName) {
-		log.Info("Apps", "Skip init because app %s is already initialized", appAndSchemaName)
-		return
-	} else {
-		log.Info("Apps", "Start initializing and registering the app %s", appAndSchemaName)
+	if err := InitContext(context.Background(), apiEndpoint, apiKey, connection, appAndSchemaName, initFunctions...); err != nil {
+		log.Fatal("Apps", "%v", err)
 	}
+}
 
-	transaction, err := db.Begin(connection)
-	if err != nil {
-		log.Fatal("Apps", "Cannot start transaction to init app %s: %v", appAndSchemaName, err)
-	}
+// InitContext works like Init, but returns a *StepError instead of exiting the process. If a function
+// fails, the transaction is rolled back and the app stays unregistered, so the init can be retried.
+func InitContext(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, initFunctions ...func(connection db.Connection) error) error {
+	return withAdvisoryLock(ctx, connection, appAndSchemaName, func(connection db.Connection) error {
+		registered, err := appRegistered(ctx, apiEndpoint, apiKey, appAndSchemaName)
+		if err != nil {
+			return &StepError{App: appAndSchemaName, Step: StepCheck, FunctionIndex: -1, Err: err}
+		}
+		if registered {
+			log.Info("Apps", "Skip init because app %s is already initialized", appAndSchemaName)
+			return nil
+		} else {
+			log.Info("Apps", "Start initializing and registering the app %s", appAndSchemaName)
+		}
 
-	for i, initFunction := range initFunctions {
-		err := initFunction(transaction)
+		if err := runTransaction(ctx, connection, initFunctions); err != nil {
+			err.App = appAndSchemaName
+			return err
+		}
+
+		err = fixPrivilege(ctx, connection, appAndSchemaName)
 		if err != nil {
-			log.Fatal("Apps", "Cannot execute function %d to init app %s: %v", i, appAndSchemaName, err)
+			log.Warn("Apps", "Cannot fix privileges for schema %s: %v", appAndSchemaName, err)
 		}
-	}
 
-	err = transaction.Commit(context.Background())
+		err = registerApp(ctx, apiEndpoint, apiKey, appAndSchemaName)
+		if err != nil {
+			return &StepError{App: appAndSchemaName, Step: StepRegister, FunctionIndex: -1, Err: err}
+		}
+
+		log.Info("Apps", "Finished initializing and registering of the app %s successfully", appAndSchemaName)
+		return nil
+	})
+}
+
+// runTransaction executes the functions within one transaction. On failure the transaction is rolled
+// back and the returned error tells which step failed. The caller has to fill in app and patch name.
+func runTransaction(ctx context.Context, connection db.Connection, functions []func(connection db.Connection) error) *StepError {
+	transaction, err := connection.Begin(ctx)
 	if err != nil {
-		log.Fatal("Apps", "Cannot commit init for app %s: %v", appAndSchemaName, err)
+		return &StepError{Step: StepBegin, FunctionIndex: -1, Err: err}
 	}
 
-	err = fixPrivilege(connection, appAndSchemaName)
-	if err != nil {
-		log.Warn("Apps", "Cannot fix privileges for schema %s: %v", appAndSchemaName, err)
+	for i, function := range functions {
+		err := function(transaction)
+		if err != nil {
+			// Roll back even if ctx is already canceled.
+			if rollbackErr := transaction.Rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
+				log.Warn("Apps", "Cannot roll back transaction: %v", rollbackErr)
+			}
+			return &StepError{Step: StepExecute, FunctionIndex: i, Err: err}
+		}
 	}
 
-	err = registerApp(apiEndpoint, apiKey, appAndSchemaName)
+	err = transaction.Commit(ctx)
 	if err != nil {
-		log.Fatal("Apps", "Cannot register app %s as initialized: %v", appAndSchemaName, err)
+		return &StepError{Step: StepCommit, FunctionIndex: -1, Err: err}
 	}
-
-	log.Info("Apps", "Finished initializing and registering of the app %s successfully", appAndSchemaName)
+	return nil
 }
 
-func fixPrivilege(connection db.Connection, appAndSchemaName string) error {
-	_, err := connection.Exec(context.Background(), fmt.Sprintf("select fixprivilege('%s','%s')", appAndSchemaName, db.Username()))
+func fixPrivilege(ctx context.Context, connection db.Connection, appAndSchemaName string) error {
+	_, err := connection.Exec(ctx, fmt.Sprintf("select fixprivilege('%s','%s')", appAndSchemaName, db.Username()))
 	if err != nil {
 		return err
 	}
 	if strings.Contains(appAndSchemaName, "-") {
-		_, err = connection.Exec(context.Background(), fmt.Sprintf("select fixprivilege('%s','%s')", strings.ReplaceAll(appAndSchemaName, "-", "_"), db.Username()))
+		_, err = connection.Exec(ctx, fmt.Sprintf("select fixprivilege('%s','%s')", strings.ReplaceAll(appAndSchemaName, "-", "_"), db.Username()))
 		if err != nil {
 			return err
 		}
 	}
 	if strings.Contains(appAndSchemaName, "_") {
-		_, err = connection.Exec(context.Background(), fmt.Sprintf("select fixprivilege('%s','%s')", strings.ReplaceAll(appAndSchemaName, "_", "-"), db.Username()))
+		_, err = connection.Exec(ctx, fmt.Sprintf("select fixprivilege('%s','%s')", strings.ReplaceAll(appAndSchemaName, "_", "-"), db.Username()))
 		if err != nil {
 			return err
 		}
@@ -156,26 +242,30 @@ func fixPrivilege(connection db.Connection, appAndSchemaName string) error {
 	return nil
 }
 
-// appRegistered checks if the app is already initialized.
-func appRegistered(apiEndpoint string, apiKey string, appName string) bool {
-	app, _, err := client.NewClient(apiEndpoint).AppsAPI.
-		GetAppByName(client.AuthenticationContext(apiKey), appName).
+// appRegistered checks if the app is already initialized. Unknown apps are not registered.
+func appRegistered(ctx context.Context, apiEndpoint string, apiKey string, appName string) (bool, error) {
+	app, res, err := client.NewClientContext(ctx, apiEndpoint).AppsAPI.
+		GetAppByName(client.AuthenticationContextWrap(ctx, apiKey), appName).
 		Execute()
-	if err != nil {
-		tools.LogError(fmt.Errorf("checking if app %v is registered: %w", appName, err))
+	err = client.NewAPIError("GetAppByName", res, err)
+	if errors.Is(err, client.ErrNotFound) {
+		return false, nil
 	}
-	if err != nil || !app.Registered.IsSet() {
-		return false
+	if err != nil {
+		err = fmt.Errorf("checking if app %v is registered: %w", appName, err)
+		tools.LogError(err)
+		return false, err
 	}
-	return *app.Registered.Get()
+	return app.Registered.IsSet() && *app.Registered.Get(), nil
 }
 
 // registerApp marks that the app is now initialized and installed.
-func registerApp(apiEndpoint string, apiKey string, appName string) error {
-	_, err := client.NewClient(apiEndpoint).AppsAPI.
-		PatchAppByName(client.AuthenticationContext(apiKey), appName).
+func registerApp(ctx context.Context, apiEndpoint string, apiKey string, appName string) error {
+	res, err := client.NewClientContext(ctx, apiEndpoint).AppsAPI.
+		PatchAppByName(client.AuthenticationContextWrap(ctx, apiKey), appName).
 		Registered(true).
 		Execute()
+	err = client.NewAPIError("PatchAppByName", res, err)
 	if err != nil {
 		tools.LogError(fmt.Errorf("registering app %v: %w", appName, err))
 	}
@@ -186,70 +276,147 @@ func registerApp(apiEndpoint string, apiKey string, appName string) error {
 // This function guarantees that everything will only run once when the patch is applied.
 // Furthermore, this function guarantees that either all database changes or no changes are committed using
 // transactions. For this you must use the connection that is passed to the function parameter.
+// Patch exits the process on failure; use PatchContext to handle errors yourself.
 func Patch(apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, patchName string, patchFunctions ...func(connection db.Connection) error) {
-	if patchApplied(apiEndpoint, apiKey, appAndSchemaName, patchName) {
-		log.Info("Apps", "Skip patching because app %s is already patched for %s", appAndSchemaName, patchName)
-		return
-	} else {
-		log.Info("Apps", "Start patching the app %s for %s", appAndSchemaName, patchName)
+	if err := PatchContext(context.Background(), apiEndpoint, apiKey, connection, appAndSchemaName, patchName, patchFunctions...); err != nil {
+		log.Fatal("Apps", "%v", err)
 	}
+}
 
-	transaction, err := db.Begin(connection)
-	if err != nil {
-		log.Fatal("Apps", "Cannot start transaction to patch %s app %s: %v", patchName, appAndSchemaName, err)
-	}
+// PatchContext works like Patch, but returns a *StepError instead of exiting the process. If a function
+// fails, the transaction is rolled back and the patch stays unapplied, so it can be retried. Like Init,
+// the patch is guarded by an advisory lock against concurrent replicas.
+func PatchContext(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, patchName string, patchFunctions ...func(connection db.Connection) error) error {
+	_, err := patch(ctx, apiEndpoint, apiKey, connection, appAndSchemaName, patchName, patchFunctions)
+	return err
+}
 
-	for i, patchFunction := range patchFunctions {
-		err := patchFunction(transaction)
+// patch applies the patch while holding the lock for it. It returns false if the patch is already applied.
+func patch(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, patchName string, patchFunctions []func(connection db.Connection) error) (bool, error) {
+	patched := false
+	err := withAdvisoryLock(ctx, connection, appAndSchemaName+"/"+patchName, func(connection db.Connection) error {
+		applied, err := patchApplied(ctx, apiEndpoint, apiKey, appAndSchemaName, patchName)
 		if err != nil {
-			log.Fatal("Apps", "Cannot execute function %d to patch %s app %s: %v", i, patchName, appAndSchemaName, err)
+			return &StepError{App: appAndSchemaName, Patch: patchName, Step: StepCheck, FunctionIndex: -1, Err: err}
 		}
-	}
+		if applied {
+			log.Info("Apps", "Skip patching because app %s is already patched for %s", appAndSchemaName, patchName)
+			return nil
+		} else {
+			log.Info("Apps", "Start patching the app %s for %s", appAndSchemaName, patchName)
+		}
+		patched = true
+		return runPatch(ctx, apiEndpoint, apiKey, connection, appAndSchemaName, patchName, patchFunctions)
+	})
+	return patched, err
+}
 
-	err = transaction.Commit(context.Background())
-	if err != nil {
-		log.Fatal("Apps", "Cannot commit patch %s for app %s: %v", patchName, appAndSchemaName, err)
+// runPatch applies the patch without checking if it is already applied.
+func runPatch(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, patchName string, patchFunctions []func(connection db.Connection) error) error {
+	if err := runTransaction(ctx, connection, patchFunctions); err != nil {
+		err.App = appAndSchemaName
+		err.Patch = patchName
+		return err
 	}
 
-	err = fixPrivilege(connection, appAndSchemaName)
+	err := fixPrivilege(ctx, connection, appAndSchemaName)
 	if err != nil {
 		log.Warn("Apps", "Cannot fix privileges for schema %s: %v", appAndSchemaName, err)
 	}
 
-	err = applyPatch(apiEndpoint, apiKey, appAndSchemaName, patchName)
+	err = applyPatch(ctx, apiEndpoint, apiKey, appAndSchemaName, patchName)
 	if err != nil {
-		log.Fatal("Apps", "Cannot register patch %s for app %s: %v", patchName, appAndSchemaName, err)
+		return &StepError{App: appAndSchemaName, Patch: patchName, Step: StepRegister, FunctionIndex: -1, Err: err}
 	}
 
 	log.Info("Apps", "Finished patching the app %s for %s successfully", appAndSchemaName, patchName)
+	return nil
 }
 
-// patchApplied checks if the patch is already applied.
-func patchApplied(apiEndpoint string, apiKey string, appName string, patchName string) bool {
-	patch, _, err := client.NewClient(apiEndpoint).AppsAPI.
-		GetPatchByName(client.AuthenticationContext(apiKey), appName, patchName).
+// patchApplied checks if the patch is already applied. Unknown patches are not applied.
+func patchApplied(ctx context.Context, apiEndpoint string, apiKey string, appName string, patchName string) (bool, error) {
+	patch, res, err := client.NewClientContext(ctx, apiEndpoint).AppsAPI.
+		GetPatchByName(client.AuthenticationContextWrap(ctx, apiKey), appName, patchName).
 		Execute()
-	if err != nil || !patch.Applied.IsSet() {
-		return false
+	err = client.NewAPIError("GetPatchByName", res, err)
+	if errors.Is(err, client.ErrNotFound) {
+		return false, nil
 	}
-	return *patch.Applied.Get()
+	if err != nil {
+		err = fmt.Errorf("checking if patch %v of app %v is applied: %w", patchName, appName, err)
+		tools.LogError(err)
+		return false, err
+	}
+	return patch.Applied.IsSet() && *patch.Applied.Get(), nil
 }
 
 // applyPatch marks that the patch is now applied.
-func applyPatch(apiEndpoint string, apiKey string, appName string, patchName string) error {
-	_, err := client.NewClient(apiEndpoint).AppsAPI.
-		PatchPatchByName(client.AuthenticationContext(apiKey), appName, patchName).
+func applyPatch(ctx context.Context, apiEndpoint string, apiKey string, appName string, patchName string) error {
+	res, err := client.NewClientContext(ctx, apiEndpoint).AppsAPI.
+		PatchPatchByName(client.AuthenticationContextWrap(ctx, apiKey), appName, patchName).
 		Apply(true).
 		Execute()
+	err = client.NewAPIError("PatchPatchByName", res, err)
 	if err != nil {
 		tools.LogError(fmt.Errorf("applying patch %v to app %v: %w", patchName, appName, err))
 	}
 	return err
 }
 
+// Rollback reverts an applied patch. The down functions run in one transaction like the patch functions
+// of Patch do. Afterward, the patch is marked as not applied, so Patch or Migrate would apply it again.
+// Rollback skips patches which are not applied and returns a *StepError on failure.
+func Rollback(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, patchName string, downFunctions ...func(connection db.Connection) error) error {
+	return withAdvisoryLock(ctx, connection, appAndSchemaName+"/"+patchName, func(connection db.Connection) error {
+		applied, err := patchApplied(ctx, apiEndpoint, apiKey, appAndSchemaName, patchName)
+		if err != nil {
+			return &StepError{App: appAndSchemaName, Patch: patchName, Rollback: true, Step: StepCheck, FunctionIndex: -1, Err: err}
+		}
+		if !applied {
+			log.Info("Apps", "Skip rollback because patch %s is not applied to app %s", patchName, appAndSchemaName)
+			return nil
+		}
+		log.Info("Apps", "Start rolling back patch %s of the app %s", patchName, appAndSchemaName)
+
+		if err := runTransaction(ctx, connection, downFunctions); err != nil {
+			err.App = appAndSchemaName
+			err.Patch = patchName
+			err.Rollback = true
+			return err
+		}
+
+		err = fixPrivilege(ctx, connection, appAndSchemaName)
+		if err != nil {
+			log.Warn("Apps", "Cannot fix privileges for schema %s: %v", appAndSchemaName, err)
+		}
+
+		err = unapplyPatch(ctx, apiEndpoint, apiKey, appAndSchemaName, patchName)
+		if err != nil {
+			return &StepError{App: appAndSchemaName, Patch: patchName, Rollback: true, Step: StepUnregister, FunctionIndex: -1, Err: err}
+		}
+
+		log.Info("Apps", "Finished rolling back patch %s of the app %s successfully", patchName, appAndSchemaName)
+		return nil
+	})
+}
+
+// unapplyPatch marks that the patch is no longer applied.
+func unapplyPatch(ctx context.Context, apiEndpoint string, apiKey string, appName string, patchName string) error {
+	res, err := client.NewClientContext(ctx, apiEndpoint).AppsAPI.
+		PatchPatchByName(client.AuthenticationContextWrap(ctx, apiKey), appName, patchName).
+		Apply(false).
+		Execute()
+	err = client.NewAPIError("PatchPatchByName", res, err)
+	if err != nil {
+		tools.LogError(fmt.Errorf("unapplying patch %v of app %v: %w", patchName, appName, err))
+	}
+	return err
+}
+
 // GetApiKey returns the API key for the given appName and tenantId.
-// TODO: Deprecated - Replace with a secure APIv2 call instead of connecting the database directly
-// Deprecated
+//
+// Deprecated: GetApiKey reads internal Eliona tables directly. Use a client.ApiKeyProvider like
+// client.EnvApiKeyProvider or client.HTTPApiKeyProvider instead.
 func GetApiKey(appName string, database *sql.DB, tenantId uuid.UUID) (string, error) {
 	const query = `
 SELECT kc.key
@@ -286,3 +453,13 @@ WHERE ea.app_name = $1
 
 	return results[0], nil
 }
+
+// DatabaseApiKeyProvider returns a client.ApiKeyProvider which reads the keys using GetApiKey. It eases
+// switching existing apps to the provider interface.
+//
+// Deprecated: Like GetApiKey, it reads internal Eliona tables directly.
+func DatabaseApiKeyProvider(appName string, database *sql.DB) client.ApiKeyProvider {
+	return client.ApiKeyProviderFunc(func(_ context.Context, tenantId uuid.UUID) (string, error) {
+		return GetApiKey(appName, database, tenantId)
+	})
+}
@@ -16,11 +16,387 @@
 package app
 
 import (
-	"github.com/stretchr/testify/assert"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/db"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestAppName(t *testing.T) {
 	assert.Equal(t, "", appNamFromFile("not_existing_file.json"))
 	assert.Equal(t, "foobar", appNamFromFile("testdata/metadata.json"))
 }
+
+// fakeAppsAPI is an in-memory stand-in for the apps endpoints of api-v2.
+type fakeAppsAPI struct {
+	mu         sync.Mutex
+	registered map[string]bool
+	applied    map[string]bool
+	// unavailable makes every request fail with 503.
+	unavailable bool
+}
+
+func newFakeAppsAPI(t *testing.T) (*fakeAppsAPI, string) {
+	f := &fakeAppsAPI{registered: map[string]bool{}, applied: map[string]bool{}}
+	server := httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(server.Close)
+	return f, server.URL
+}
+
+func (f *fakeAppsAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case f.unavailable:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": parts[1], "registered": f.registered[parts[1]]})
+	case len(parts) == 2 && r.Method == http.MethodPatch:
+		f.registered[parts[1]] = r.URL.Query().Get("registered") == "true"
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 4 && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]any{"appName": parts[1], "name": parts[3], "applied": f.applied[parts[3]]})
+	case len(parts) == 4 && r.Method == http.MethodPatch:
+		f.applied[parts[3]] = r.URL.Query().Get("apply") == "true"
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type fakeTx struct {
+	pgx.Tx
+	connection *fakeConnection
+	committed  bool
+	rolledBack bool
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tx.connection.Exec(ctx, sql, args...)
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	tx.rolledBack = true
+	return nil
+}
+
+// fakeConnection records executed statements and the transactions it hands out. If locked is set,
+// advisory locks are held by another instance.
+type fakeConnection struct {
+	statements   []string
+	transactions []*fakeTx
+	locked       bool
+}
+
+func (c *fakeConnection) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	c.statements = append(c.statements, sql)
+	return nil, nil
+}
+
+func (c *fakeConnection) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	if strings.Contains(sql, "pg_try_advisory_lock") {
+		return &fakeRows{values: []bool{!c.locked}}, nil
+	}
+	return nil, errors.New("query not supported")
+}
+
+type fakeRows struct {
+	pgx.Rows
+	values []bool
+}
+
+func (r *fakeRows) Next() bool {
+	return len(r.values) > 0
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	*dest[0].(*bool) = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+func (r *fakeRows) Close() {}
+
+func (r *fakeRows) Err() error {
+	return nil
+}
+
+func (c *fakeConnection) Begin(context.Context) (pgx.Tx, error) {
+	tx := &fakeTx{connection: c}
+	c.transactions = append(c.transactions, tx)
+	return tx, nil
+}
+
+func execSql(sql string) func(db.Connection) error {
+	return func(connection db.Connection) error {
+		_, err := connection.Exec(context.Background(), sql)
+		return err
+	}
+}
+
+func TestInitContext(t *testing.T) {
+	api, endpoint := newFakeAppsAPI(t)
+	connection := &fakeConnection{}
+
+	err := InitContext(context.Background(), endpoint, "key", connection, "foobar", execSql("create schema foobar"))
+	assert.NoError(t, err)
+	assert.True(t, api.registered["foobar"])
+	assert.True(t, connection.transactions[0].committed)
+	assert.Contains(t, connection.statements, "create schema foobar")
+
+	// A registered app is skipped.
+	err = InitContext(context.Background(), endpoint, "key", connection, "foobar", execSql("create schema foobar"))
+	assert.NoError(t, err)
+	assert.Len(t, connection.transactions, 1)
+}
+
+func TestInitContextFailure(t *testing.T) {
+	api, endpoint := newFakeAppsAPI(t)
+	connection := &fakeConnection{}
+	failure := errors.New("boom")
+
+	err := InitContext(context.Background(), endpoint, "key", connection, "foobar",
+		execSql("create schema foobar"),
+		func(db.Connection) error { return failure })
+
+	var stepErr *StepError
+	assert.ErrorAs(t, err, &stepErr)
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, StepExecute, stepErr.Step)
+	assert.Equal(t, 1, stepErr.FunctionIndex)
+	assert.Equal(t, "foobar", stepErr.App)
+	assert.True(t, connection.transactions[0].rolledBack)
+	assert.False(t, connection.transactions[0].committed)
+	assert.False(t, api.registered["foobar"])
+}
+
+func TestPatchContextFailure(t *testing.T) {
+	api, endpoint := newFakeAppsAPI(t)
+	connection := &fakeConnection{}
+
+	err := PatchContext(context.Background(), endpoint, "key", connection, "foobar", "010100",
+		func(db.Connection) error { return errors.New("boom") })
+
+	var stepErr *StepError
+	assert.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "010100", stepErr.Patch)
+	assert.Equal(t, "cannot execute function 0 to patch 010100 app foobar: boom", err.Error())
+	assert.False(t, api.applied["010100"])
+
+	err = PatchContext(context.Background(), endpoint, "key", connection, "foobar", "010100", execSql("alter table foo"))
+	assert.NoError(t, err)
+	assert.True(t, api.applied["010100"])
+}
+
+func TestCheckFailure(t *testing.T) {
+	api, endpoint := newFakeAppsAPI(t)
+	api.unavailable = true
+	connection := &fakeConnection{}
+
+	err := InitContext(context.Background(), endpoint, "key", connection, "foobar", execSql("create schema foobar"))
+	var stepErr *StepError
+	assert.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, StepCheck, stepErr.Step)
+
+	err = PatchContext(context.Background(), endpoint, "key", connection, "foobar", "010100", execSql("alter table foo"))
+	assert.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, StepCheck, stepErr.Step)
+	assert.Equal(t, "010100", stepErr.Patch)
+
+	// No step may run when the state is unknown.
+	assert.Empty(t, connection.transactions)
+	assert.NotContains(t, connection.statements, "create schema foobar")
+	assert.NotContains(t, connection.statements, "alter table foo")
+}
+
+func TestParseVersion(t *testing.T) {
+	version, err := ParseVersion("010203")
+	assert.NoError(t, err)
+	assert.Equal(t, Version{Major: 1, Minor: 2, Patch: 3}, version)
+
+	version, err = ParseVersion("v9.2.0-rc1+build5")
+	assert.NoError(t, err)
+	assert.Equal(t, Version{Major: 9, Minor: 2, PreRelease: "rc1"}, version)
+
+	_, err = ParseVersion("init")
+	assert.Error(t, err)
+
+	assert.Equal(t, -1, Version{Major: 1, PreRelease: "rc1"}.Compare(Version{Major: 1}))
+	assert.Equal(t, 1, Version{Major: 1, Minor: 10}.Compare(Version{Major: 1, Minor: 9}))
+}
+
+func TestApplyMigrations(t *testing.T) {
+	api, endpoint := newFakeAppsAPI(t)
+	api.applied["010000"] = true
+	connection := &fakeConnection{}
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "010100.sql"), []byte("alter table a"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "010000.sql"), []byte("create table a"), 0o644))
+	migrations, err := LoadMigrations(dir)
+	assert.NoError(t, err)
+	migrations = append(migrations,
+		Migration{Name: "1.3.0", Functions: []func(db.Connection) error{execSql("alter table c")}},
+		Migration{Name: "1.2.0", Functions: []func(db.Connection) error{func(db.Connection) error { return errors.New("boom") }}})
+
+	report, err := ApplyMigrations(context.Background(), endpoint, "key", connection, "foobar", migrations...)
+	assert.Error(t, err)
+	assert.Equal(t, MigrationReport{Applied: []string{"010100"}, Skipped: []string{"010000"}, Pending: []string{"1.3.0"}, Failed: "1.2.0"}, report)
+	assert.Contains(t, connection.statements, "alter table a")
+	assert.NotContains(t, connection.statements, "create table a")
+	assert.True(t, api.applied["010100"])
+	assert.False(t, api.applied["1.2.0"])
+
+	_, err = ApplyMigrations(context.Background(), endpoint, "key", connection, "foobar", Migration{Name: "latest"})
+	assert.Error(t, err)
+
+	noop := []func(db.Connection) error{execSql("select 1")}
+	_, err = ApplyMigrations(context.Background(), endpoint, "key", connection, "foobar",
+		Migration{Name: "010100", Functions: noop}, Migration{Name: "1.1.0", Functions: noop})
+	assert.ErrorContains(t, err, "duplicate patch 1.1.0: same version as 010100")
+}
+
+func TestRollbackMigration(t *testing.T) {
+	api, endpoint := newFakeAppsAPI(t)
+	api.applied["010100"] = true
+	connection := &fakeConnection{}
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "010100.sql"), []byte("alter table a add b int"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "010100.down.sql"), []byte("alter table a drop b"), 0o644))
+	migrations, err := LoadMigrations(dir)
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 1)
+	assert.Len(t, migrations[0].Down, 1)
+
+	err = RollbackMigration(context.Background(), endpoint, "key", connection, "foobar", dir, "010100")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alter table a drop b"}, connection.statements[:1])
+	assert.False(t, api.applied["010100"])
+
+	// Not applied patches are skipped.
+	err = Rollback(context.Background(), endpoint, "key", connection, "foobar", "010100", func(db.Connection) error { return errors.New("boom") })
+	assert.NoError(t, err)
+
+	api.applied["010100"] = true
+	err = Rollback(context.Background(), endpoint, "key", connection, "foobar", "010100", func(db.Connection) error { return errors.New("boom") })
+	var stepErr *StepError
+	assert.ErrorAs(t, err, &stepErr)
+	assert.True(t, stepErr.Rollback)
+	assert.Equal(t, "cannot execute function 0 to roll back patch 010100 app foobar: boom", err.Error())
+	assert.True(t, api.applied["010100"])
+
+	assert.Error(t, RollbackMigration(context.Background(), endpoint, "key", connection, "foobar", dir, "020000"))
+
+	// Down-only migrations are ignored when applying and rejected when rolling back.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "010200.down.sql"), []byte("drop table c"), 0o644))
+	report, err := Migrate(context.Background(), endpoint, "key", connection, "foobar", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrationReport{Skipped: []string{"010100"}}, report)
+	assert.ErrorContains(t, RollbackMigration(context.Background(), endpoint, "key", connection, "foobar", dir, "1.2.0"), "no patch functions")
+}
+
+func TestDryRunPatch(t *testing.T) {
+	api, endpoint := newFakeAppsAPI(t)
+	connection := &fakeConnection{}
+
+	plan, err := DryRunPatch(context.Background(), endpoint, "key", connection, "foobar", "010100",
+		execSql("alter table a add b int"),
+		func(connection db.Connection) error {
+			connection.(PlanRecorder).RecordPlan("asset type", "weather")
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []PlanAction{{Kind: "sql", Description: "alter table a add b int"}, {Kind: "asset type", Description: "weather"}}, plan.Actions)
+	assert.True(t, connection.transactions[0].rolledBack)
+	assert.False(t, connection.transactions[0].committed)
+	assert.False(t, api.applied["010100"])
+	assert.Contains(t, plan.String(), "2. asset type: weather")
+
+	api.applied["010100"] = true
+	plan, err = DryRunPatch(context.Background(), endpoint, "key", connection, "foobar", "010100", execSql("alter table a add b int"))
+	assert.NoError(t, err)
+	assert.True(t, plan.Skipped)
+}
+
+func TestInitContextLockTimeout(t *testing.T) {
+	ctx := WithLockTimeout(context.Background(), 50*time.Millisecond)
+	api, endpoint := newFakeAppsAPI(t)
+	connection := &fakeConnection{locked: true}
+
+	err := InitContext(ctx, endpoint, "key", connection, "foobar", execSql("create schema foobar"))
+	assert.ErrorIs(t, err, ErrLockTimeout)
+	assert.Empty(t, connection.transactions)
+	assert.False(t, api.registered["foobar"])
+
+	connection.locked = false
+	err = InitContext(context.Background(), endpoint, "key", connection, "foobar", execSql("create schema foobar"))
+	assert.NoError(t, err)
+	assert.Contains(t, connection.statements, "select pg_advisory_unlock($1)")
+}
+
+func TestValidateMetadata(t *testing.T) {
+	t.Setenv("CONNECTION_STRING", "postgres://")
+	t.Setenv("API_ENDPOINT", "http://api-v2:3000/v2")
+	t.Setenv("API_TOKEN", "secret")
+	assert.NoError(t, validateMetadataFile("testdata/metadata.json"))
+
+	err := validateMetadataFile("testdata/metadata_invalid.json")
+	var metadataErr *MetadataError
+	assert.ErrorAs(t, err, &metadataErr)
+	assert.Equal(t, []string{
+		`unknown field "elionaMinVerison"`,
+		"elionaMinVersion is missing",
+		"displayName has no English (en) entry",
+		"environment variable FOOBAR_NOT_DEFINED from useEnvironment is not set",
+	}, metadataErr.Problems)
+}
+
+func TestMetadataIsCompatibleWith(t *testing.T) {
+	metadata := Metadata{ElionaMinVersion: "9.2.0"}
+	compatible, err := metadata.IsCompatibleWith("v9.10.1")
+	assert.NoError(t, err)
+	assert.True(t, compatible)
+
+	compatible, err = metadata.IsCompatibleWith("9.2.0-rc1")
+	assert.NoError(t, err)
+	assert.False(t, compatible)
+
+	_, err = Metadata{ElionaMinVersion: "nine"}.IsCompatibleWith("9.2.0")
+	assert.Error(t, err)
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version": "v9.1.4", "commit_hash": "abc"}`))
+	}))
+	defer server.Close()
+
+	version, err := ElionaVersion(context.Background(), server.URL, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "v9.1.4", version)
+
+	assert.NoError(t, CheckCompatibility(context.Background(), server.URL, "key", Metadata{Name: "foobar", ElionaMinVersion: "9.1.0"}))
+	err = CheckCompatibility(context.Background(), server.URL, "key", Metadata{Name: "foobar", ElionaMinVersion: "9.2.0"})
+	assert.ErrorIs(t, err, ErrIncompatibleVersion)
+}
@@ -0,0 +1,77 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eliona-smart-building-assistant/go-eliona-api-client/v3/tools"
+	"github.com/eliona-smart-building-assistant/go-eliona/v2/client"
+	"github.com/eliona-smart-building-assistant/go-utils/db"
+)
+
+// ErrIncompatibleVersion is returned if the running Eliona is older than the elionaMinVersion of the app.
+var ErrIncompatibleVersion = errors.New("incompatible eliona version")
+
+// ElionaVersion returns the version of the running Eliona instance.
+func ElionaVersion(ctx context.Context, apiEndpoint string, apiKey string) (string, error) {
+	info, res, err := client.NewClientContext(ctx, apiEndpoint).VersionAPI.
+		GetVersion(client.AuthenticationContextWrap(ctx, apiKey)).
+		Execute()
+	err = client.NewAPIError("GetVersion", res, err)
+	if err != nil {
+		err = fmt.Errorf("getting eliona version: %w", err)
+		tools.LogError(err)
+		return "", err
+	}
+	version, ok := info["version"].(string)
+	if !ok || version == "" {
+		return "", fmt.Errorf("getting eliona version: no version in response %v", info)
+	}
+	return version, nil
+}
+
+// CheckCompatibility compares the version of the running Eliona with the elionaMinVersion from the
+// metadata. If Eliona is too old, the returned error wraps ErrIncompatibleVersion.
+func CheckCompatibility(ctx context.Context, apiEndpoint string, apiKey string, metadata Metadata) error {
+	version, err := ElionaVersion(ctx, apiEndpoint, apiKey)
+	if err != nil {
+		return err
+	}
+	compatible, err := metadata.IsCompatibleWith(version)
+	if err != nil {
+		return err
+	}
+	if !compatible {
+		return fmt.Errorf("%w: app %s requires eliona %s or newer, but eliona %s is running", ErrIncompatibleVersion, metadata.Name, metadata.ElionaMinVersion, version)
+	}
+	return nil
+}
+
+// RequireCompatibleVersion returns a function which checks the compatibility of the app defined in
+// metadata.json with the running Eliona. Pass it as first function to Init or Patch to refuse the
+// init or patch before any schema changes are made.
+func RequireCompatibleVersion(apiEndpoint string, apiKey string) func(connection db.Connection) error {
+	return func(db.Connection) error {
+		metadata, _, err := GetMetadata()
+		if err != nil {
+			return err
+		}
+		return CheckCompatibility(context.Background(), apiEndpoint, apiKey, metadata)
+	}
+}
@@ -0,0 +1,167 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/eliona-smart-building-assistant/go-utils/db"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Plan describes what Init or Patch would do. It is returned by DryRunInit and DryRunPatch.
+type Plan struct {
+	App     string
+	Patch   string
+	Skipped bool
+	Actions []PlanAction
+}
+
+// PlanAction is a single change recorded during a dry run, e.g. a SQL statement (kind "sql") or
+// an upserted asset type (kind "asset type").
+type PlanAction struct {
+	Kind        string
+	Description string
+}
+
+// String returns the plan in a human-readable form.
+func (p Plan) String() string {
+	var b strings.Builder
+	target := fmt.Sprintf("init of app %s", p.App)
+	if p.Patch != "" {
+		target = fmt.Sprintf("patch %s of app %s", p.Patch, p.App)
+	}
+	if p.Skipped {
+		fmt.Fprintf(&b, "Skip %s because it is already done\n", target)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Dry run of %s with %d actions\n", target, len(p.Actions))
+	for i, action := range p.Actions {
+		fmt.Fprintf(&b, "%4d. %s: %s\n", i+1, action.Kind, action.Description)
+	}
+	return b.String()
+}
+
+// DryRunInit executes the init functions like InitContext does, but rolls back the transaction in any
+// case and does not register the app. Functions provided by this library, like ExecSqlFile or
+// asset.InitAssetTypeFile, are recorded in the returned plan instead of calling the API. Own functions
+// must only use the passed connection, otherwise their changes are not covered by the dry run.
+func DryRunInit(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, initFunctions ...func(connection db.Connection) error) (Plan, error) {
+	plan := Plan{App: appAndSchemaName}
+	registered, err := appRegistered(ctx, apiEndpoint, apiKey, appAndSchemaName)
+	if err != nil {
+		return plan, &StepError{App: appAndSchemaName, Step: StepCheck, FunctionIndex: -1, Err: err}
+	}
+	if registered {
+		plan.Skipped = true
+		return plan, nil
+	}
+	if err := dryRun(ctx, connection, &plan, initFunctions); err != nil {
+		err.App = appAndSchemaName
+		return plan, err
+	}
+	return plan, nil
+}
+
+// DryRunPatch executes the patch functions like PatchContext does, but rolls back the transaction in any
+// case and does not mark the patch as applied. See DryRunInit for details.
+func DryRunPatch(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, patchName string, patchFunctions ...func(connection db.Connection) error) (Plan, error) {
+	plan := Plan{App: appAndSchemaName, Patch: patchName}
+	applied, err := patchApplied(ctx, apiEndpoint, apiKey, appAndSchemaName, patchName)
+	if err != nil {
+		return plan, &StepError{App: appAndSchemaName, Patch: patchName, Step: StepCheck, FunctionIndex: -1, Err: err}
+	}
+	if applied {
+		plan.Skipped = true
+		return plan, nil
+	}
+	if err := dryRun(ctx, connection, &plan, patchFunctions); err != nil {
+		err.App = appAndSchemaName
+		err.Patch = patchName
+		return plan, err
+	}
+	return plan, nil
+}
+
+func dryRun(ctx context.Context, connection db.Connection, plan *Plan, functions []func(connection db.Connection) error) *StepError {
+	transaction, err := connection.Begin(ctx)
+	if err != nil {
+		return &StepError{Step: StepBegin, FunctionIndex: -1, Err: err}
+	}
+	defer func() {
+		if err := transaction.Rollback(context.WithoutCancel(ctx)); err != nil {
+			log.Warn("Apps", "Cannot roll back dry run transaction: %v", err)
+		}
+	}()
+
+	recorder := &dryRunTx{Tx: transaction, plan: plan, mutex: &sync.Mutex{}}
+	for i, function := range functions {
+		if err := function(recorder); err != nil {
+			return &StepError{Step: StepExecute, FunctionIndex: i, Err: err}
+		}
+	}
+	return nil
+}
+
+// PlanRecorder is implemented by the connection which DryRunInit and DryRunPatch pass to init and patch
+// functions. Functions changing things outside the database, like asset.InitAssetTypeFile, record what
+// they would do instead.
+type PlanRecorder interface {
+	RecordPlan(kind string, description string)
+}
+
+// dryRunTx is the connection passed to the functions during a dry run. It records all statements
+// and offers RecordPlan to functions which would otherwise change things outside the database.
+type dryRunTx struct {
+	pgx.Tx
+	plan  *Plan
+	mutex *sync.Mutex
+}
+
+// RecordPlan adds an action to the plan of the dry run.
+func (tx *dryRunTx) RecordPlan(kind string, description string) {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	tx.plan.Actions = append(tx.plan.Actions, PlanAction{Kind: kind, Description: description})
+}
+
+func (tx *dryRunTx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	tx.RecordPlan("sql", strings.TrimSpace(sql))
+	return tx.Tx.Exec(ctx, sql, arguments...)
+}
+
+func (tx *dryRunTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	tx.RecordPlan("sql", strings.TrimSpace(sql))
+	return tx.Tx.Query(ctx, sql, args...)
+}
+
+func (tx *dryRunTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	tx.RecordPlan("sql", strings.TrimSpace(sql))
+	return tx.Tx.QueryRow(ctx, sql, args...)
+}
+
+func (tx *dryRunTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	nested, err := tx.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &dryRunTx{Tx: nested, plan: tx.plan, mutex: tx.mutex}, nil
+}
@@ -0,0 +1,129 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/db"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// DefaultLockTimeout is the maximum time Init, Patch and Rollback wait for another replica of the app
+// which currently initializes the app or applies the same patch. Use WithLockTimeout to change it per
+// call.
+const DefaultLockTimeout = 5 * time.Minute
+
+// ErrLockTimeout is returned if the advisory lock could not be acquired within the lock timeout.
+var ErrLockTimeout = errors.New("timeout waiting for advisory lock")
+
+type lockTimeoutKey struct{}
+
+// WithLockTimeout returns a context which lets the InitContext, PatchContext and Rollback calls using it
+// wait up to the given time for the advisory lock instead of DefaultLockTimeout. A deadline of the
+// context limits the wait anyway.
+func WithLockTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, lockTimeoutKey{}, timeout)
+}
+
+func lockTimeout(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(lockTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return DefaultLockTimeout
+}
+
+const lockPollInterval = 500 * time.Millisecond
+
+// withAdvisoryLock runs the function while holding a PostgreSQL advisory lock for the given name, so
+// that only one replica at a time checks, runs and registers an init or patch. The lock is held on a
+// single database session, which is passed to the function.
+func withAdvisoryLock(ctx context.Context, connection db.Connection, name string, function func(connection db.Connection) error) error {
+	if pool, ok := connection.(*pgxpool.Pool); ok {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			return fmt.Errorf("acquiring connection for lock %s: %w", name, err)
+		}
+		defer conn.Release()
+		connection = conn
+	}
+
+	// Within a transaction the lock is released together with the transaction.
+	_, transactional := connection.(pgx.Tx)
+	lockFunction, unlockFunction := "pg_try_advisory_lock", "pg_advisory_unlock"
+	if transactional {
+		lockFunction = "pg_try_advisory_xact_lock"
+	}
+
+	key := lockKey(name)
+	waitCtx, cancel := context.WithTimeout(ctx, lockTimeout(ctx))
+	defer cancel()
+	for {
+		locked, err := tryLock(waitCtx, connection, lockFunction, key)
+		if err != nil && waitCtx.Err() == nil {
+			return fmt.Errorf("acquiring lock %s: %w", name, err)
+		}
+		if locked {
+			break
+		}
+		log.Debug("Apps", "Waiting for lock %s held by another instance", name)
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("acquiring lock %s: %w", name, ErrLockTimeout)
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	if !transactional {
+		defer func() {
+			if _, err := connection.Exec(context.WithoutCancel(ctx), "select "+unlockFunction+"($1)", key); err != nil {
+				log.Warn("Apps", "Cannot release lock %s: %v", name, err)
+			}
+		}()
+	}
+	return function(connection)
+}
+
+func tryLock(ctx context.Context, connection db.Connection, lockFunction string, key int64) (bool, error) {
+	rows, err := connection.Query(ctx, "select "+lockFunction+"($1)", key)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	var locked bool
+	if rows.Next() {
+		if err := rows.Scan(&locked); err != nil {
+			return false, err
+		}
+	}
+	return locked, rows.Err()
+}
+
+// lockKey maps the lock name to the 64-bit key used by PostgreSQL advisory locks.
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte("eliona-app:" + name))
+	return int64(hash.Sum64())
+}
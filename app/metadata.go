@@ -0,0 +1,139 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MetadataError lists all problems found in the metadata.
+type MetadataError struct {
+	Problems []string
+}
+
+func (e *MetadataError) Error() string {
+	return fmt.Sprintf("invalid metadata: %s", strings.Join(e.Problems, "; "))
+}
+
+// ValidateMetadata reads the file metadata.json and validates it. Besides the checks of Metadata.Validate,
+// it reports fields which are not known, e.g. because of typos.
+func ValidateMetadata() error {
+	return validateMetadataFile("metadata.json")
+}
+
+func validateMetadataFile(filename string) error {
+	metadata, data, err := getMetadataFromFile(filename)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed unmarhalling %s: %w", filename, err)
+	}
+	known := metadataFieldNames()
+	for _, name := range sortedKeys(fields) {
+		if !known[name] {
+			problems = append(problems, fmt.Sprintf("unknown field %q", name))
+		}
+	}
+
+	var metadataErr *MetadataError
+	if err := metadata.Validate(); err != nil {
+		if !errors.As(err, &metadataErr) {
+			return err
+		}
+		problems = append(problems, metadataErr.Problems...)
+	}
+	if len(problems) > 0 {
+		return &MetadataError{Problems: problems}
+	}
+	return nil
+}
+
+// Validate checks that the required fields name, elionaMinVersion and an English displayName are set,
+// that elionaMinVersion is a semantic version and that all variables listed in useEnvironment are set
+// in the environment. It returns a *MetadataError listing all problems at once.
+func (m Metadata) Validate() error {
+	var problems []string
+	if strings.TrimSpace(m.Name) == "" {
+		problems = append(problems, "name is missing")
+	}
+	if m.ElionaMinVersion == "" {
+		problems = append(problems, "elionaMinVersion is missing")
+	} else if _, err := m.MinVersion(); err != nil {
+		problems = append(problems, fmt.Sprintf("elionaMinVersion: %v", err))
+	}
+	if strings.TrimSpace(m.DisplayName["en"]) == "" {
+		problems = append(problems, "displayName has no English (en) entry")
+	}
+	for _, variable := range m.UseEnvironment {
+		if _, ok := os.LookupEnv(variable); !ok {
+			problems = append(problems, fmt.Sprintf("environment variable %s from useEnvironment is not set", variable))
+		}
+	}
+	if len(problems) > 0 {
+		return &MetadataError{Problems: problems}
+	}
+	return nil
+}
+
+// MinVersion returns the parsed elionaMinVersion.
+func (m Metadata) MinVersion() (Version, error) {
+	return ParseVersion(m.ElionaMinVersion)
+}
+
+// IsCompatibleWith returns true if the given Eliona version is at least the elionaMinVersion of the app.
+// If no elionaMinVersion is defined, every version is compatible.
+func (m Metadata) IsCompatibleWith(elionaVersion string) (bool, error) {
+	if m.ElionaMinVersion == "" {
+		return true, nil
+	}
+	minVersion, err := m.MinVersion()
+	if err != nil {
+		return false, fmt.Errorf("elionaMinVersion: %w", err)
+	}
+	version, err := ParseVersion(elionaVersion)
+	if err != nil {
+		return false, fmt.Errorf("eliona version: %w", err)
+	}
+	return version.Compare(minVersion) >= 0, nil
+}
+
+func metadataFieldNames() map[string]bool {
+	names := make(map[string]bool)
+	metadataType := reflect.TypeOf(Metadata{})
+	for i := 0; i < metadataType.NumField(); i++ {
+		name, _, _ := strings.Cut(metadataType.Field(i).Tag.Get("json"), ",")
+		names[name] = true
+	}
+	return names
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,231 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eliona-smart-building-assistant/go-utils/db"
+)
+
+// Migration is a patch which can be applied by Migrate. The name is the patch name registered in
+// Eliona and must be a version like "010100" or "1.1.0", which defines the order of the patches.
+// The optional Down functions revert the patch, see RollbackMigration.
+type Migration struct {
+	Name      string
+	Functions []func(connection db.Connection) error
+	Down      []func(connection db.Connection) error
+}
+
+// MigrationReport tells what a Migrate run did. Applied contains the patches applied in this run,
+// Skipped the patches applied before, Pending the patches not attempted because Failed failed.
+type MigrationReport struct {
+	Applied []string
+	Skipped []string
+	Pending []string
+	Failed  string
+}
+
+var (
+	registeredMigrationsMutex sync.Mutex
+	registeredMigrations      []Migration
+)
+
+// RegisterPatch registers patch functions written in Go. Migrate applies them together with the
+// patch files found in the patch directory. Call it from an init() function or at the top of main().
+func RegisterPatch(patchName string, patchFunctions ...func(connection db.Connection) error) {
+	registeredMigrationsMutex.Lock()
+	defer registeredMigrationsMutex.Unlock()
+	registeredMigrations = append(registeredMigrations, Migration{Name: patchName, Functions: patchFunctions})
+}
+
+// RegisterRollback registers Go functions which revert the patch with the given name. They are used
+// by RollbackMigration.
+func RegisterRollback(patchName string, downFunctions ...func(connection db.Connection) error) {
+	registeredMigrationsMutex.Lock()
+	defer registeredMigrationsMutex.Unlock()
+	registeredMigrations = append(registeredMigrations, Migration{Name: patchName, Down: downFunctions})
+}
+
+// LoadMigrations returns a migration for each *.sql file in dir. The file name without extension
+// is used as patch name, e.g. database/patches/010100.sql becomes patch "010100". A file with the
+// suffix .down.sql, e.g. 010100.down.sql, is used as down step of the patch with the same name.
+func LoadMigrations(dir string) ([]Migration, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("glob patch files in %s: %w", dir, err)
+	}
+	var migrations []Migration
+	index := make(map[string]int)
+	for _, path := range paths {
+		name, down := strings.CutSuffix(filepath.Base(path), ".down.sql")
+		if !down {
+			name = strings.TrimSuffix(name, ".sql")
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(migrations)
+			index[name] = i
+			migrations = append(migrations, Migration{Name: name})
+		}
+		if down {
+			migrations[i].Down = []func(connection db.Connection) error{ExecSqlFile(path)}
+		} else {
+			migrations[i].Functions = []func(connection db.Connection) error{ExecSqlFile(path)}
+		}
+	}
+	return migrations, nil
+}
+
+// migrationKey identifies the patch with the given name, so that e.g. "010100" and "1.1.0" are the same
+// patch.
+func migrationKey(name string) string {
+	if version, err := ParseVersion(name); err == nil {
+		return version.String()
+	}
+	return name
+}
+
+// collectMigrations merges the migrations found in patchDir with the registered ones.
+func collectMigrations(patchDir string) ([]Migration, error) {
+	migrations, err := LoadMigrations(patchDir)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(migrations))
+	for i, migration := range migrations {
+		index[migrationKey(migration.Name)] = i
+	}
+
+	registeredMigrationsMutex.Lock()
+	defer registeredMigrationsMutex.Unlock()
+	for _, registered := range registeredMigrations {
+		key := migrationKey(registered.Name)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(migrations)
+			migrations = append(migrations, registered)
+			continue
+		}
+		existing := &migrations[i]
+		if len(registered.Functions) > 0 && len(existing.Functions) > 0 || len(registered.Down) > 0 && len(existing.Down) > 0 {
+			return nil, fmt.Errorf("patch %s is defined twice", registered.Name)
+		}
+		existing.Functions = append(existing.Functions, registered.Functions...)
+		existing.Down = append(existing.Down, registered.Down...)
+	}
+	return migrations, nil
+}
+
+// Migrate applies all missing patches found in patchDir and registered by RegisterPatch. See
+// ApplyMigrations for details.
+func Migrate(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, patchDir string) (MigrationReport, error) {
+	migrations, err := collectMigrations(patchDir)
+	if err != nil {
+		return MigrationReport{}, err
+	}
+	return ApplyMigrations(ctx, apiEndpoint, apiKey, connection, appAndSchemaName, migrations...)
+}
+
+// RollbackMigration reverts the patch with the given name using the down step found in patchDir or
+// registered by RegisterRollback. See Rollback for details.
+func RollbackMigration(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, patchDir string, patchName string) error {
+	migrations, err := collectMigrations(patchDir)
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migrationKey(migration.Name) != migrationKey(patchName) {
+			continue
+		}
+		if len(migration.Down) == 0 {
+			return fmt.Errorf("patch %s has no down step", patchName)
+		}
+		if len(migration.Functions) == 0 {
+			return fmt.Errorf("patch %s has a down step but no patch functions", patchName)
+		}
+		return Rollback(ctx, apiEndpoint, apiKey, connection, appAndSchemaName, migration.Name, migration.Down...)
+	}
+	return fmt.Errorf("patch %s not found", patchName)
+}
+
+// ApplyMigrations sorts the migrations by version and applies the ones not applied yet, each in its own
+// transaction like PatchContext does. It stops at the first failing patch and returns the error together
+// with a report of what was applied, skipped and left pending. Migrations having only down functions are
+// ignored.
+func ApplyMigrations(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, appAndSchemaName string, migrations ...Migration) (MigrationReport, error) {
+	var report MigrationReport
+	applicable := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if len(migration.Functions) == 0 && len(migration.Down) > 0 {
+			continue
+		}
+		applicable = append(applicable, migration)
+	}
+	sorted, err := sortMigrations(applicable)
+	if err != nil {
+		return report, err
+	}
+
+	for i, migration := range sorted {
+		patched, err := patch(ctx, apiEndpoint, apiKey, connection, appAndSchemaName, migration.Name, migration.Functions)
+		if err != nil {
+			report.Failed = migration.Name
+			for _, pending := range sorted[i+1:] {
+				report.Pending = append(report.Pending, pending.Name)
+			}
+			return report, err
+		}
+		if patched {
+			report.Applied = append(report.Applied, migration.Name)
+		} else {
+			report.Skipped = append(report.Skipped, migration.Name)
+		}
+	}
+	return report, nil
+}
+
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	versions := make(map[string]Version, len(migrations))
+	names := make(map[Version]string, len(migrations))
+	for _, migration := range migrations {
+		if len(migration.Functions) == 0 {
+			return nil, fmt.Errorf("patch %s has no patch functions", migration.Name)
+		}
+		version, err := ParseVersion(migration.Name)
+		if err != nil {
+			return nil, fmt.Errorf("patch name: %w", err)
+		}
+		// "010100" and "1.1.0" are the same patch.
+		if name, duplicate := names[version]; duplicate {
+			return nil, fmt.Errorf("duplicate patch %s: same version as %s", migration.Name, name)
+		}
+		names[version] = migration.Name
+		versions[migration.Name] = version
+	}
+
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return versions[sorted[i].Name].Compare(versions[sorted[j].Name]) < 0
+	})
+	return sorted, nil
+}
@@ -0,0 +1,88 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version like 1.2.3 or 1.2.3-rc1.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string
+}
+
+// ParseVersion parses semantic versions like "1.2.3", "v1.2.3" or "1.2.3-rc1". Missing minor and patch
+// numbers count as zero. Build metadata after "+" is ignored. Patch names in the form "MMmmpp"
+// (e.g. "010100" for 1.1.0) are accepted as well.
+func ParseVersion(s string) (Version, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	value, _, _ = strings.Cut(value, "+")
+	value, preRelease, _ := strings.Cut(value, "-")
+
+	var parts []string
+	if len(value) == 6 && !strings.Contains(value, ".") {
+		parts = []string{value[0:2], value[2:4], value[4:6]}
+	} else {
+		parts = strings.Split(value, ".")
+	}
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q: too many parts", s)
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return Version{}, fmt.Errorf("invalid version %q: %q is not a number", s, part)
+		}
+		numbers[i] = number
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2], PreRelease: preRelease}, nil
+}
+
+// Compare returns -1, 0 or +1 if v is lower, equal or higher than other. A pre-release is lower than
+// the release with the same numbers.
+func (v Version) Compare(other Version) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.PreRelease == other.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case other.PreRelease == "":
+		return -1
+	}
+	return strings.Compare(v.PreRelease, other.PreRelease)
+}
+
+func (v Version) String() string {
+	if v.PreRelease != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", v.Major, v.Minor, v.Patch, v.PreRelease)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
@@ -16,9 +16,9 @@
 package asset
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"net/http"
 	"path/filepath"
 
 	"github.com/eliona-smart-building-assistant/go-eliona-api-client/v3/tools"
@@ -29,57 +29,114 @@ import (
 	"github.com/eliona-smart-building-assistant/go-utils/db"
 )
 
-var ErrNotFound = errors.New("not found")
+// ErrNotFound is returned if an asset does not exist. It is the same value as client.ErrNotFound.
+var ErrNotFound = client.ErrNotFound
 
 // UpsertAssetType insert or, when already exist, updates an asset type
 func UpsertAssetType(apiEndpoint string, apiKey string, assetType api.AssetType) error {
-	_, _, err := client.NewClient(apiEndpoint).AssetTypesAPI.
-		PutAssetType(client.AuthenticationContext(apiKey)).
+	return UpsertAssetTypeContext(context.Background(), apiEndpoint, apiKey, assetType)
+}
+
+// UpsertAssetTypeContext works like UpsertAssetType using the given context.
+func UpsertAssetTypeContext(ctx context.Context, apiEndpoint string, apiKey string, assetType api.AssetType) error {
+	_, res, err := client.NewClientContext(ctx, apiEndpoint).AssetTypesAPI.
+		PutAssetType(client.AuthenticationContextWrap(ctx, apiKey)).
 		Expansions([]string{"AssetType.attributes"}). // take values of attributes also
 		AssetType(assetType).
 		Execute()
+	err = client.NewAPIError("PutAssetType", res, err)
 	if err != nil {
 		tools.LogError(fmt.Errorf("upserting asset type %v: %w", assetType.Name, err))
 	}
 	return err
 }
 
-func getAsset(apiEndpoint string, apiKey string, assetId int32) (*api.Asset, error) {
-	asset, res, err := client.NewClient(apiEndpoint).AssetsAPI.
-		GetAssetById(client.AuthenticationContext(apiKey), assetId).
+// getAsset fetches the asset without logging; callers log failures with their own context.
+func getAsset(ctx context.Context, apiEndpoint string, apiKey string, assetId int32) (*api.Asset, error) {
+	asset, res, err := client.NewClientContext(ctx, apiEndpoint).AssetsAPI.
+		GetAssetById(client.AuthenticationContextWrap(ctx, apiKey), assetId).
 		Execute()
+	// res is nil if the request failed without a response, e.g. because the connection was refused.
+	err = client.NewAPIError("GetAssetById", res, err)
 	if err != nil {
-		tools.LogError(fmt.Errorf("getting asset %v: %w", assetId, err))
+		return nil, err
 	}
-	if res.StatusCode == http.StatusNotFound {
-		return nil, ErrNotFound
+	return asset, nil
+}
+
+// Existence is the result of an existence check. ExistenceUnknown means that the check failed, e.g.
+// because Eliona was not reachable.
+type Existence int
+
+const (
+	ExistenceUnknown Existence = iota
+	Exists
+	NotExists
+)
+
+func (e Existence) String() string {
+	switch e {
+	case Exists:
+		return "exists"
+	case NotExists:
+		return "not exists"
+	default:
+		return "unknown"
 	}
-	return asset, err
 }
 
-// ExistAsset returns true, if the given asset id exists in eliona
-func ExistAsset(apiEndpoint string, apiKey string, assetId int32) (bool, error) {
-	_, err := getAsset(apiEndpoint, apiKey, assetId)
+// CheckAssetExistence checks if the given asset id exists in eliona. The error is set if and only if
+// the result is ExistenceUnknown.
+func CheckAssetExistence(apiEndpoint string, apiKey string, assetId int32) (Existence, error) {
+	return CheckAssetExistenceContext(context.Background(), apiEndpoint, apiKey, assetId)
+}
+
+// CheckAssetExistenceContext works like CheckAssetExistence using the given context.
+func CheckAssetExistenceContext(ctx context.Context, apiEndpoint string, apiKey string, assetId int32) (Existence, error) {
+	_, err := getAsset(ctx, apiEndpoint, apiKey, assetId)
 	if errors.Is(err, ErrNotFound) {
-		return false, nil
+		return NotExists, nil
 	}
 	if err != nil {
-		tools.LogError(fmt.Errorf("checking if asset %v exists: %w", assetId, err))
+		err = fmt.Errorf("checking if asset %v exists: %w", assetId, err)
+		tools.LogError(err)
+		return ExistenceUnknown, err
 	}
-	return true, err
+	return Exists, nil
+}
+
+// ExistAsset returns true, if the given asset id exists in eliona. If the existence cannot be
+// determined, it returns false and the error.
+func ExistAsset(apiEndpoint string, apiKey string, assetId int32) (bool, error) {
+	return ExistAssetContext(context.Background(), apiEndpoint, apiKey, assetId)
+}
+
+// ExistAssetContext works like ExistAsset using the given context.
+func ExistAssetContext(ctx context.Context, apiEndpoint string, apiKey string, assetId int32) (bool, error) {
+	existence, err := CheckAssetExistenceContext(ctx, apiEndpoint, apiKey, assetId)
+	return existence == Exists, err
 }
 
 // UpsertAsset inserts or updates an asset and returns the id
 func UpsertAsset(apiEndpoint string, apiKey string, asset api.Asset) (*int32, error) {
-	upsertedAsset, _, err := client.NewClient(apiEndpoint).AssetsAPI.
-		PutAsset(client.AuthenticationContext(apiKey)).
+	return UpsertAssetContext(context.Background(), apiEndpoint, apiKey, asset)
+}
+
+// UpsertAssetContext works like UpsertAsset using the given context.
+func UpsertAssetContext(ctx context.Context, apiEndpoint string, apiKey string, asset api.Asset) (*int32, error) {
+	upsertedAsset, res, err := client.NewClientContext(ctx, apiEndpoint).AssetsAPI.
+		PutAsset(client.AuthenticationContextWrap(ctx, apiKey)).
 		Asset(asset).Execute()
+	err = client.NewAPIError("PutAsset", res, err)
 	if err != nil {
 		tools.LogError(fmt.Errorf("upserting asset %v: %w", asset.Name, err))
 	}
 	if err != nil {
 		return nil, err
 	}
+	if id := upsertedAsset.Id.Get(); id != nil {
+		DefaultExistenceCache.Set(cacheEndpoint(ctx, apiEndpoint), *id, true)
+	}
 	return upsertedAsset.Id.Get(), nil
 }
 
@@ -88,61 +145,114 @@ func UpsertAsset(apiEndpoint string, apiKey string, asset api.Asset) (*int32, er
 // Assets must be ordered - parents must come before their children, otherwise
 // relations will not be created.
 func UpsertAssetsBulkGAI(apiEndpoint string, apiKey string, assets []api.Asset) ([]api.Asset, error) {
-	upsertedAssets, _, err := client.NewClient(apiEndpoint).AssetsAPI.
-		PutBulkAssets(client.AuthenticationContext(apiKey)).
+	return UpsertAssetsBulkGAIContext(context.Background(), apiEndpoint, apiKey, assets)
+}
+
+// UpsertAssetsBulkGAIContext works like UpsertAssetsBulkGAI using the given context. The assets are sent
+// in chunks of DefaultChunkSize; use UpsertAssetsBulkGAIChunkedContext to find out which assets failed.
+// If some chunks fail, the assets of the succeeded chunks are returned together with the error.
+func UpsertAssetsBulkGAIContext(ctx context.Context, apiEndpoint string, apiKey string, assets []api.Asset) ([]api.Asset, error) {
+	result := UpsertAssetsBulkGAIChunkedContext(ctx, apiEndpoint, apiKey, assets, ChunkOptions{})
+	return result.Succeeded, result.Err()
+}
+
+func putBulkAssets(ctx context.Context, apiEndpoint string, apiKey string, assets []api.Asset) ([]api.Asset, error) {
+	upsertedAssets, res, err := client.NewClientContext(ctx, apiEndpoint).AssetsAPI.
+		PutBulkAssets(client.AuthenticationContextWrap(ctx, apiKey)).
 		Asset(assets).
 		IdentifyBy(string(api.ASSET_IDENTIFY_BY_GAI_SITE_ID)).
 		Execute()
+	err = client.NewAPIError("PutBulkAssets", res, err)
 	if err != nil {
 		e := fmt.Errorf("upserting assets: %w", err)
 		tools.LogError(e)
 		return nil, e
 	}
+	for _, upsertedAsset := range upsertedAssets {
+		if id := upsertedAsset.Id.Get(); id != nil {
+			DefaultExistenceCache.Set(cacheEndpoint(ctx, apiEndpoint), *id, true)
+		}
+	}
 	return upsertedAssets, nil
 }
 
 // UpsertAssetTypeAttribute insert or updates an asset and returns the id
 func UpsertAssetTypeAttribute(apiEndpoint string, apiKey string, attribute api.AssetTypeAttribute) error {
-	_, _, err := client.NewClient(apiEndpoint).AssetTypesAPI.
-		PutAssetTypeAttribute(client.AuthenticationContext(apiKey), *attribute.AssetTypeName.Get()).
+	return UpsertAssetTypeAttributeContext(context.Background(), apiEndpoint, apiKey, attribute)
+}
+
+// UpsertAssetTypeAttributeContext works like UpsertAssetTypeAttribute using the given context.
+func UpsertAssetTypeAttributeContext(ctx context.Context, apiEndpoint string, apiKey string, attribute api.AssetTypeAttribute) error {
+	_, res, err := client.NewClientContext(ctx, apiEndpoint).AssetTypesAPI.
+		PutAssetTypeAttribute(client.AuthenticationContextWrap(ctx, apiKey), *attribute.AssetTypeName.Get()).
 		AssetTypeAttribute(attribute).
 		Execute()
+	err = client.NewAPIError("PutAssetTypeAttribute", res, err)
 	if err != nil {
 		tools.LogError(fmt.Errorf("upserting asset type attribute %v: %w", attribute.Name, err))
 	}
 	return err
 }
 
+// planRecorder is implemented by the connection passed to init and patch functions during a dry run
+// (see app.DryRunInit). Instead of changing things, the functions record what they would do.
+type planRecorder interface {
+	RecordPlan(kind string, description string)
+}
+
 // InitAssetType inserts or updates the given asset type.
 func InitAssetType(apiEndpoint string, apiKey string, assetType api.AssetType) func(db.Connection) error {
-	return func(db.Connection) error {
-		return UpsertAssetType(apiEndpoint, apiKey, assetType)
+	return InitAssetTypeContext(context.Background(), apiEndpoint, apiKey, assetType)
+}
+
+// InitAssetTypeContext works like InitAssetType. The returned function uses the given context.
+func InitAssetTypeContext(ctx context.Context, apiEndpoint string, apiKey string, assetType api.AssetType) func(db.Connection) error {
+	return func(connection db.Connection) error {
+		if recorder, ok := connection.(planRecorder); ok {
+			recorder.RecordPlan("asset type", assetType.Name)
+			return nil
+		}
+		return UpsertAssetTypeContext(ctx, apiEndpoint, apiKey, assetType)
 	}
 }
 
-func initAssetTypeFile(apiEndpoint string, apiKey string, path string) error {
+func initAssetTypeFile(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, path string) error {
 	assetType, err := common.UnmarshalFile[api.AssetType](path)
 	if err != nil {
 		return fmt.Errorf("unmarshalling file %s: %v", path, err)
 	}
-	return UpsertAssetType(apiEndpoint, apiKey, assetType)
+	if recorder, ok := connection.(planRecorder); ok {
+		recorder.RecordPlan("asset type", fmt.Sprintf("%s from %s", assetType.Name, path))
+		return nil
+	}
+	return UpsertAssetTypeContext(ctx, apiEndpoint, apiKey, assetType)
 }
 
 // InitAssetTypeFile inserts or updates the asset type build from the content of the given file.
 func InitAssetTypeFile(apiEndpoint string, apiKey string, path string) func(db.Connection) error {
-	return func(db.Connection) error {
-		return initAssetTypeFile(apiEndpoint, apiKey, path)
+	return InitAssetTypeFileContext(context.Background(), apiEndpoint, apiKey, path)
+}
+
+// InitAssetTypeFileContext works like InitAssetTypeFile. The returned function uses the given context.
+func InitAssetTypeFileContext(ctx context.Context, apiEndpoint string, apiKey string, path string) func(db.Connection) error {
+	return func(connection db.Connection) error {
+		return initAssetTypeFile(ctx, apiEndpoint, apiKey, connection, path)
 	}
 }
 
 func InitAssetTypeFiles(apiEndpoint string, apiKey string, pattern string) func(db.Connection) error {
-	return func(db.Connection) error {
+	return InitAssetTypeFilesContext(context.Background(), apiEndpoint, apiKey, pattern)
+}
+
+// InitAssetTypeFilesContext works like InitAssetTypeFiles. The returned function uses the given context.
+func InitAssetTypeFilesContext(ctx context.Context, apiEndpoint string, apiKey string, pattern string) func(db.Connection) error {
+	return func(connection db.Connection) error {
 		paths, err := filepath.Glob(pattern)
 		if err != nil {
 			return fmt.Errorf("glob file pattern %s: %v", pattern, err)
 		}
 		for _, path := range paths {
-			err := initAssetTypeFile(apiEndpoint, apiKey, path)
+			err := initAssetTypeFile(ctx, apiEndpoint, apiKey, connection, path)
 			if err != nil {
 				return fmt.Errorf("initializing asset type %s: %v", path, err)
 			}
@@ -16,6 +16,7 @@
 package asset
 
 import (
+	"context"
 	"fmt"
 
 	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
@@ -37,10 +38,15 @@ type AssetLikeWithParentReferences interface {
 // CreateAssetsBulk creates assets within the asset structure provided.
 // It ensures that parent assets are created before their children by sorting the assets accordingly.
 func CreateAssetsBulk(apiEndpoint string, apiKey string, assetLikes []AssetLikeWithParentReferences) (createdCnt int, err error) {
-	return createAssets(apiEndpoint, apiKey, assetLikes)
+	return CreateAssetsBulkContext(context.Background(), apiEndpoint, apiKey, assetLikes)
 }
 
-func createAssets(apiEndpoint string, apiKey string, assetLikes []AssetLikeWithParentReferences) (createdCnt int, err error) {
+// CreateAssetsBulkContext works like CreateAssetsBulk using the given context.
+func CreateAssetsBulkContext(ctx context.Context, apiEndpoint string, apiKey string, assetLikes []AssetLikeWithParentReferences) (createdCnt int, err error) {
+	return createAssets(ctx, apiEndpoint, apiKey, assetLikes)
+}
+
+func createAssets(ctx context.Context, apiEndpoint string, apiKey string, assetLikes []AssetLikeWithParentReferences) (createdCnt int, err error) {
 	sortedAssetLikes, err := sortAssetLikesByDependencies(assetLikes)
 	if err != nil {
 		return 0, fmt.Errorf("sorting assetLikes: %v", err)
@@ -50,7 +56,7 @@ func createAssets(apiEndpoint string, apiKey string, assetLikes []AssetLikeWithP
 	for _, assetLike := range sortedAssetLikes {
 		apiAssets = append(apiAssets, assetLikeToApiAsset(assetLike))
 	}
-	result, err := UpsertAssetsBulkGAI(apiEndpoint, apiKey, apiAssets)
+	result, err := UpsertAssetsBulkGAIContext(ctx, apiEndpoint, apiKey, apiAssets)
 	if err != nil {
 		return 0, fmt.Errorf("upserting bulk assetLikes: %v", err)
 	}
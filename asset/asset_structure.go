@@ -16,6 +16,7 @@
 package asset
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -53,6 +54,11 @@ type AssetLike interface {
 // NOTE: Prefer using CreateAssetsBulk method, avoiding complexity and too much
 // juggling of asset structure.
 func CreateAssets(apiEndpoint string, apiKey string, root Root) (createdCnt int, err error) {
+	return CreateAssetsContext(context.Background(), apiEndpoint, apiKey, root)
+}
+
+// CreateAssetsContext works like CreateAssets using the given context.
+func CreateAssetsContext(ctx context.Context, apiEndpoint string, apiKey string, root Root) (createdCnt int, err error) {
 	var assetLikesToCreate []AssetLikeWithParentReferences
 
 	err = collectAssetLikesToCreate(root, "", "", &assetLikesToCreate, map[string]bool{})
@@ -60,7 +66,7 @@ func CreateAssets(apiEndpoint string, apiKey string, root Root) (createdCnt int,
 		return 0, fmt.Errorf("collecting asset likes to create: %v", err)
 	}
 
-	createdCnt, err = createAssets(apiEndpoint, apiKey, assetLikesToCreate)
+	createdCnt, err = createAssets(ctx, apiEndpoint, apiKey, assetLikesToCreate)
 	if err != nil {
 		return 0, fmt.Errorf("creating assets: %v", err)
 	}
@@ -74,6 +80,11 @@ func CreateAssets(apiEndpoint string, apiKey string, root Root) (createdCnt int,
 // NOTE: Prefer using CreateAssetsBulk method, avoiding complexity and too much
 // juggling of asset structure.
 func CreateAssetsAndUpsertData(apiEndpoint string, apiKey string, root Root, ts *time.Time, clientReference *string) (createdCnt int, err error) {
+	return CreateAssetsAndUpsertDataContext(context.Background(), apiEndpoint, apiKey, root, ts, clientReference)
+}
+
+// CreateAssetsAndUpsertDataContext works like CreateAssetsAndUpsertData using the given context.
+func CreateAssetsAndUpsertDataContext(ctx context.Context, apiEndpoint string, apiKey string, root Root, ts *time.Time, clientReference *string) (createdCnt int, err error) {
 	var assetLikesToCreate []AssetLikeWithParentReferences
 	var dataToUpsert []Data
 
@@ -82,14 +93,14 @@ func CreateAssetsAndUpsertData(apiEndpoint string, apiKey string, root Root, ts
 		return 0, fmt.Errorf("collecting asset likes and data to create: %v", err)
 	}
 
-	createdCnt, err = createAssets(apiEndpoint, apiKey, assetLikesToCreate)
+	createdCnt, err = createAssets(ctx, apiEndpoint, apiKey, assetLikesToCreate)
 	if err != nil {
 		return 0, fmt.Errorf("creating assets: %v", err)
 	}
 
 	// Upsert data for all assets (including those that already existed)
 	for _, data := range dataToUpsert {
-		err := UpsertAssetDataIfAssetExists(apiEndpoint, apiKey, data)
+		err := UpsertAssetDataIfAssetExistsContext(ctx, apiEndpoint, apiKey, data)
 		if err != nil {
 			return createdCnt, fmt.Errorf("upserting data: %v", err)
 		}
@@ -16,10 +16,22 @@
 package asset
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+	"github.com/eliona-smart-building-assistant/go-eliona/v2/client"
 	"github.com/eliona-smart-building-assistant/go-utils/common"
 	"github.com/stretchr/testify/assert"
 )
@@ -49,3 +61,793 @@ func TestErrorLogging(t *testing.T) {
 		fmt.Printf("upserting asset %+v into Eliona: %v", a, err)
 	}
 }
+
+// fakeAssetsAPI is an in-memory stand-in for the asset and data endpoints of api-v2.
+type fakeAssetsAPI struct {
+	mu            sync.Mutex
+	assets        map[int32]api.Asset
+	assetRequests int
+	bulkRequests  int
+	data          []api.Data
+	deleted       []int32
+	// undeletable assets fail to delete with 500.
+	undeletable map[int32]bool
+}
+
+// put stores the asset, identified by its ID or by its GAI, and assigns a new ID to new assets.
+func (f *fakeAssetsAPI) put(asset api.Asset) api.Asset {
+	if !asset.Id.IsSet() || asset.Id.Get() == nil {
+		id := int32(len(f.assets) + len(f.deleted) + 1)
+		for existingId, existing := range f.assets {
+			if existing.GlobalAssetIdentifier == asset.GlobalAssetIdentifier {
+				id = existingId
+			}
+		}
+		asset.Id = *api.NewNullableInt32(common.Ptr(id))
+	}
+	f.assets[*asset.Id.Get()] = asset
+	return asset
+}
+
+func newFakeAssetsAPI(t *testing.T, existing ...int32) (*fakeAssetsAPI, string) {
+	f := &fakeAssetsAPI{assets: map[int32]api.Asset{}}
+	for _, id := range existing {
+		f.assets[id] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(id)), AssetType: "test", GlobalAssetIdentifier: strconv.Itoa(int(id))}
+	}
+	server := httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(server.Close)
+	return f, server.URL
+}
+
+func (f *fakeAssetsAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case len(parts) == 2 && parts[0] == "assets" && r.Method == http.MethodGet:
+		f.assetRequests++
+		id, _ := strconv.Atoi(parts[1])
+		asset, ok := f.assets[int32(id)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(asset)
+	case len(parts) == 1 && parts[0] == "assets" && r.Method == http.MethodGet:
+		assets := make([]api.Asset, 0, len(f.assets))
+		for _, asset := range f.assets {
+			if assetType := r.URL.Query().Get("assetTypeName"); assetType == "" || assetType == asset.AssetType {
+				assets = append(assets, asset)
+			}
+		}
+		sort.Slice(assets, func(i, j int) bool { return assets[i].GetId() < assets[j].GetId() })
+		_ = json.NewEncoder(w).Encode(assets)
+	case len(parts) == 1 && parts[0] == "assets" && r.Method == http.MethodPut:
+		var asset api.Asset
+		if err := json.NewDecoder(r.Body).Decode(&asset); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(f.put(asset))
+	case len(parts) == 1 && parts[0] == "assets-bulk" && r.Method == http.MethodPut:
+		var assets []api.Asset
+		if err := json.NewDecoder(r.Body).Decode(&assets); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, asset := range assets {
+			if asset.AssetType == "" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		for i := range assets {
+			assets[i] = f.put(assets[i])
+		}
+		_ = json.NewEncoder(w).Encode(assets)
+	case len(parts) == 2 && parts[0] == "assets" && r.Method == http.MethodDelete:
+		id, _ := strconv.Atoi(parts[1])
+		if _, ok := f.assets[int32(id)]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if f.undeletable[int32(id)] {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		delete(f.assets, int32(id))
+		f.deleted = append(f.deleted, int32(id))
+		w.WriteHeader(http.StatusNoContent)
+	case parts[0] == "data" && r.Method == http.MethodGet:
+		datas := []api.Data{}
+		for _, data := range f.data {
+			if strconv.Itoa(int(data.AssetId)) == r.URL.Query().Get("assetId") {
+				datas = append(datas, data)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(datas)
+	case parts[0] == "data-bulk" && r.Method == http.MethodPut:
+		var data []api.Data
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.bulkRequests++
+		for _, d := range data {
+			if _, invalid := d.Data["invalid"]; invalid {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if _, unavailable := d.Data["unavailable"]; unavailable {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		f.data = append(f.data, data...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCheckAssetExistence(t *testing.T) {
+	_, url := newFakeAssetsAPI(t, 1)
+
+	existence, err := CheckAssetExistence(url, "key", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Exists, existence)
+
+	existence, err = CheckAssetExistence(url, "key", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, NotExists, existence)
+
+	exists, err := ExistAsset(url, "key", 2)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestCheckAssetExistenceUnknown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	existence, err := CheckAssetExistence(server.URL, "key", 1)
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Equal(t, ExistenceUnknown, existence)
+
+	// Connection refused: there is no response at all.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	existence, err = CheckAssetExistence(closed.URL, "key", 1)
+	assert.Error(t, err)
+	assert.Equal(t, ExistenceUnknown, existence)
+
+	exists, err := ExistAsset(closed.URL, "key", 1)
+	assert.Error(t, err)
+	assert.False(t, exists)
+}
+
+func TestUpsertDataBulkIfAssetExists(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t, 1, 2)
+	datas := []api.Data{
+		{AssetId: 1, Subtype: api.SUBTYPE_INPUT, Data: map[string]any{"a": 1}},
+		{AssetId: 2, Subtype: api.SUBTYPE_INPUT, Data: map[string]any{"a": 2}},
+		{AssetId: 3, Subtype: api.SUBTYPE_INPUT, Data: map[string]any{"a": 3}},
+		{AssetId: 1, Subtype: api.SUBTYPE_INFO, Data: map[string]any{"b": 1}},
+		{AssetId: 3, Subtype: api.SUBTYPE_INFO, Data: map[string]any{"b": 3}},
+	}
+
+	assert.NoError(t, UpsertDataBulkIfAssetExists(url, "key", datas))
+	assert.Equal(t, 3, fake.assetRequests)
+	assert.Len(t, fake.data, 3)
+	for _, data := range fake.data {
+		assert.NotEqual(t, int32(3), data.AssetId)
+	}
+
+	// The second call is answered from the cache, but the missing asset is checked again.
+	assert.NoError(t, UpsertDataBulkIfAssetExists(url, "key", datas))
+	assert.Equal(t, 4, fake.assetRequests)
+	assert.Len(t, fake.data, 6)
+}
+
+func TestUpsertDataBulkIfAssetExistsCanceled(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t, 1, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := UpsertDataBulkIfAssetExistsContext(ctx, url, "key", []api.Data{
+		{AssetId: 1, Subtype: api.SUBTYPE_INPUT, Data: map[string]any{"a": 1}},
+		{AssetId: 2, Subtype: api.SUBTYPE_INPUT, Data: map[string]any{"a": 2}},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, fake.data)
+}
+
+func TestAssetExistenceCache(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t, 1, 2)
+	cache := NewAssetExistenceCache(time.Hour, time.Hour)
+	ctx := context.Background()
+
+	exists, err := cache.Exists(ctx, url, "key", 3)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	fake.mu.Lock()
+	fake.assets[3] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(3)))}
+	fake.mu.Unlock()
+	exists, _ = cache.Exists(ctx, url, "key", 3)
+	assert.False(t, exists, "negative result is cached")
+	assert.Equal(t, 1, fake.assetRequests)
+
+	cache.Invalidate(url, 3)
+	exists, _ = cache.Exists(ctx, url, "key", 3)
+	assert.True(t, exists)
+	assert.Equal(t, 2, fake.assetRequests)
+
+	cache.InvalidateAll()
+	assert.NoError(t, cache.WarmUp(ctx, url, "key"))
+	for _, id := range []int32{1, 2, 3} {
+		exists, _ = cache.Exists(ctx, url, "key", id)
+		assert.True(t, exists)
+	}
+	assert.Equal(t, 2, fake.assetRequests)
+
+	noNegatives := NewAssetExistenceCache(time.Hour, 0)
+	_, _ = noNegatives.Exists(ctx, url, "key", 4)
+	_, _ = noNegatives.Exists(ctx, url, "key", 4)
+	assert.Equal(t, 4, fake.assetRequests)
+
+	literal := &AssetExistenceCache{TTL: time.Hour}
+	exists, err = literal.Exists(ctx, url, "key", 1)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, _ = literal.Exists(ctx, url, "key", 1)
+	assert.True(t, exists)
+	assert.Equal(t, 5, fake.assetRequests)
+}
+
+func TestUpsertDataBulkChunked(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t)
+	var datas []api.Data
+	for i := int32(1); i <= 10; i++ {
+		datas = append(datas, api.Data{AssetId: i, Subtype: api.SUBTYPE_INPUT, Data: map[string]any{"value": i}})
+	}
+	datas[6].Data = map[string]any{"invalid": true}
+
+	result := UpsertDataBulkChunked(url, "key", datas, ChunkOptions{ChunkSize: 4})
+	assert.Equal(t, 3, fake.bulkRequests)
+	assert.Len(t, result.Succeeded, 6)
+	assert.Len(t, result.Failed, 4)
+	assert.ErrorIs(t, result.Err(), client.ErrValidation)
+
+	fake.mu.Lock()
+	fake.bulkRequests = 0
+	fake.mu.Unlock()
+	result = UpsertDataBulkChunked(url, "key", datas, ChunkOptions{ChunkSize: 4, Bisect: true})
+	assert.Len(t, result.Succeeded, 9)
+	if assert.Len(t, result.Failed, 1) {
+		assert.Equal(t, int32(7), result.Failed[0].Item.AssetId)
+		assert.ErrorIs(t, result.Failed[0].Err, client.ErrValidation)
+	}
+	// 3 chunks, the failed chunk split into 2 halves and the failed half into 2 items
+	assert.Equal(t, 7, fake.bulkRequests)
+
+	assert.Error(t, UpsertDataBulk(url, "key", datas))
+
+	// Server errors are not bisected.
+	fake.mu.Lock()
+	fake.bulkRequests = 0
+	fake.mu.Unlock()
+	datas[6].Data = map[string]any{"unavailable": true}
+	result = UpsertDataBulkChunked(url, "key", datas, ChunkOptions{ChunkSize: 4, Bisect: true})
+	assert.Len(t, result.Failed, 4)
+	assert.ErrorIs(t, result.Err(), client.ErrServer)
+	assert.Equal(t, 3, fake.bulkRequests)
+}
+
+func TestUpsertAssetsBulkGAIPartialFailure(t *testing.T) {
+	defer func(size int) { DefaultChunkSize = size }(DefaultChunkSize)
+	DefaultChunkSize = 2
+	_, url := newFakeAssetsAPI(t)
+	assets := []api.Asset{
+		{GlobalAssetIdentifier: "a", AssetType: "device"},
+		{GlobalAssetIdentifier: "b", AssetType: "device"},
+		{GlobalAssetIdentifier: "c", AssetType: "device"},
+		{GlobalAssetIdentifier: "d"},
+	}
+	upserted, err := UpsertAssetsBulkGAI(url, "key", assets)
+	assert.ErrorIs(t, err, client.ErrValidation)
+	if assert.Len(t, upserted, 2) {
+		assert.Equal(t, "a", upserted[0].GlobalAssetIdentifier)
+		assert.NotZero(t, upserted[0].GetId())
+	}
+}
+
+type testAsset struct {
+	gai        string
+	name       string
+	assetType  string
+	locational string
+	site       string
+	assetID    int32
+}
+
+func (a *testAsset) GetName() string                { return a.name }
+func (a *testAsset) GetDescription() string         { return "" }
+func (a *testAsset) GetAssetType() string           { return a.assetType }
+func (a *testAsset) GetGAI() string                 { return a.gai }
+func (a *testAsset) GetLocationalParentGAI() string { return a.locational }
+func (a *testAsset) GetFunctionalParentGAI() string { return "" }
+func (a *testAsset) GetSiteID() string              { return a.site }
+func (a *testAsset) SetAssetID(assetID int32) error {
+	a.assetID = assetID
+	return nil
+}
+
+func TestSync(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t)
+	fake.assets[1] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(1))), GlobalAssetIdentifier: "dev-1", Name: *api.NewNullableString(common.Ptr("Device 1")), Description: *api.NewNullableString(common.Ptr("")), AssetType: "device"}
+	fake.assets[2] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(2))), GlobalAssetIdentifier: "dev-2", Name: *api.NewNullableString(common.Ptr("Old name")), Description: *api.NewNullableString(common.Ptr("")), AssetType: "device"}
+	fake.assets[3] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(3))), GlobalAssetIdentifier: "dev-3", AssetType: "device"}
+	fake.assets[4] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(4))), GlobalAssetIdentifier: "other", AssetType: "other"}
+
+	desired := []*testAsset{
+		{gai: "dev-1", name: "Device 1", assetType: "device"},
+		{gai: "dev-2", name: "Device 2", assetType: "device"},
+		{gai: "dev-4", name: "Device 4", assetType: "device", locational: "dev-1"},
+	}
+	assetLikes := make([]AssetLikeWithParentReferences, 0, len(desired))
+	for _, d := range desired {
+		assetLikes = append(assetLikes, d)
+	}
+
+	report, err := Sync(url, "key", assetLikes, SyncOptions{AssetType: "device", OrphanPolicy: MarkOrphansInactive, DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev-4"}, report.Created)
+	assert.Empty(t, report.Deactivated)
+	assert.Len(t, fake.assets, 4)
+
+	report, err = Sync(url, "key", assetLikes, SyncOptions{AssetType: "device", OrphanPolicy: MarkOrphansInactive})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev-4"}, report.Created)
+	assert.Equal(t, []string{"dev-2"}, report.Updated)
+	assert.Equal(t, []string{"dev-1"}, report.Unchanged)
+	assert.Equal(t, []string{"dev-3"}, report.Orphans)
+	assert.Equal(t, []string{"dev-3"}, report.Deactivated)
+	assert.Equal(t, int32(1), desired[0].assetID)
+	assert.Equal(t, int32(2), desired[1].assetID)
+	assert.NotZero(t, desired[2].assetID)
+	assert.Equal(t, "Device 2", *fake.assets[2].Name.Get())
+	assert.Equal(t, []string{DefaultInactiveTag}, fake.assets[3].Tags)
+
+	// Nothing is deleted if the limit is exceeded or no assets are desired.
+	_, err = Sync(url, "key", assetLikes[:1], SyncOptions{AssetType: "device", OrphanPolicy: DeleteOrphans, MaxDeletes: 2})
+	assert.ErrorIs(t, err, ErrDeleteLimitExceeded)
+	_, err = Sync(url, "key", nil, SyncOptions{AssetType: "device", OrphanPolicy: DeleteOrphans})
+	assert.ErrorIs(t, err, ErrNoDesiredAssets)
+	assert.Empty(t, fake.deleted)
+
+	report, err = Sync(url, "key", assetLikes[:1], SyncOptions{AssetType: "device", OrphanPolicy: DeleteOrphans})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"dev-2", "dev-3", "dev-4"}, report.Deleted)
+	assert.Contains(t, fake.assets, int32(4))
+
+	_, err = Sync(url, "key", assetLikes, SyncOptions{})
+	assert.Error(t, err)
+}
+
+func TestSyncPartialFailure(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t)
+	desired := []AssetLikeWithParentReferences{
+		&testAsset{gai: "dev-1", name: "Device 1", assetType: "device"},
+		&testAsset{gai: "dev-2", name: "Device 2"},
+	}
+
+	report, err := Sync(url, "key", desired, SyncOptions{AssetType: "device", Chunk: ChunkOptions{ChunkSize: 1}})
+	assert.ErrorIs(t, err, client.ErrValidation)
+	assert.Equal(t, []string{"dev-1"}, report.Created)
+	assert.Equal(t, []string{"dev-2"}, report.Failed)
+	assert.NotZero(t, desired[0].(*testAsset).assetID)
+	assert.Len(t, fake.assets, 1)
+
+	fake.assets[10] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(10))), GlobalAssetIdentifier: "dev-10", AssetType: "device"}
+	fake.assets[11] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(11))), GlobalAssetIdentifier: "dev-11", AssetType: "device"}
+	fake.undeletable = map[int32]bool{11: true}
+	report, err = Sync(url, "key", desired[:1], SyncOptions{AssetType: "device", OrphanPolicy: DeleteOrphans})
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Equal(t, []string{"dev-10", "dev-11"}, report.Orphans)
+	assert.Equal(t, []string{"dev-10"}, report.Deleted)
+}
+
+func TestSyncAmbiguousGAI(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t)
+	fake.assets[1] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(1))), GlobalAssetIdentifier: "dev-1", AssetType: "device", ProjectId: "1"}
+	fake.assets[2] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(2))), GlobalAssetIdentifier: "dev-1", AssetType: "device", ProjectId: "2"}
+
+	desired := []AssetLikeWithParentReferences{&testAsset{gai: "dev-1", name: "Device 1", assetType: "device"}}
+	report, err := Sync(url, "key", desired, SyncOptions{AssetType: "device", OrphanPolicy: DeleteOrphans})
+	assert.ErrorIs(t, err, ErrAmbiguousGAI)
+	assert.Empty(t, report.Orphans)
+	assert.Empty(t, fake.deleted)
+	assert.Len(t, fake.assets, 2)
+}
+
+func TestSetAssetIDs(t *testing.T) {
+	newAsset := func(id int32, site string) api.Asset {
+		return api.Asset{Id: *api.NewNullableInt32(common.Ptr(id)), SiteId: *api.NewNullableString(common.Ptr(site)), GlobalAssetIdentifier: "dev"}
+	}
+	siteA := &testAsset{gai: "dev", site: "a"}
+	siteB := &testAsset{gai: "dev", site: "b"}
+	assert.NoError(t, setAssetIDs([]AssetLikeWithParentReferences{siteA, siteB}, []api.Asset{newAsset(2, "b"), newAsset(1, "a")}))
+	assert.Equal(t, int32(1), siteA.assetID)
+	assert.Equal(t, int32(2), siteB.assetID)
+
+	assert.Error(t, setAssetIDs([]AssetLikeWithParentReferences{&testAsset{gai: "dev", site: "c"}}, []api.Asset{newAsset(1, "a")}))
+}
+
+func TestDeleteAssets(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t)
+	newAsset := func(id int32, parent int32) api.Asset {
+		asset := api.Asset{Id: *api.NewNullableInt32(common.Ptr(id)), GlobalAssetIdentifier: fmt.Sprintf("gai-%d", id), AssetType: "device"}
+		if parent != 0 {
+			asset.ParentLocationalAssetId = *api.NewNullableInt32(common.Ptr(parent))
+		}
+		return asset
+	}
+	// 1 ─┬─ 2 ── 4
+	//    └─ 3
+	// 5
+	for _, asset := range []api.Asset{newAsset(1, 0), newAsset(2, 1), newAsset(3, 1), newAsset(4, 2), newAsset(5, 0)} {
+		fake.assets[asset.GetId()] = asset
+	}
+
+	report, err := DeleteAssetTree(url, "key", 1, Locational, DeleteOptions{MaxAssets: 3})
+	assert.ErrorIs(t, err, ErrDeleteLimitExceeded)
+	assert.Len(t, report.Assets, 4)
+	assert.Empty(t, fake.deleted)
+
+	report, err = DeleteAssetTree(url, "key", 1, Locational, DeleteOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, report.Assets, 4)
+	assert.Empty(t, report.Deleted)
+	assert.Empty(t, fake.deleted)
+
+	// A failure stops the deletion and the report tells what was deleted before.
+	fake.undeletable = map[int32]bool{2: true}
+	report, err = DeleteAssetTree(url, "key", 1, Locational, DeleteOptions{})
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Len(t, report.Assets, 4)
+	assert.Equal(t, report.Assets[:2], report.Deleted)
+	assert.Equal(t, []int32{4, 3}, fake.deleted)
+
+	fake.undeletable = nil
+	report, err = DeleteAssetTree(url, "key", 1, Locational, DeleteOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, report.Deleted, 2)
+	assert.Equal(t, []int32{4, 3, 2, 1}, fake.deleted)
+	assert.Len(t, fake.assets, 1)
+
+	// Assets without site are not part of a requested site.
+	report, err = DeleteAssetsByGAI(url, "key", "site-a", []string{"gai-5"}, DeleteOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gai-5"}, report.NotFound)
+	assert.Len(t, fake.assets, 1)
+
+	report, err = DeleteAssetsByGAI(url, "key", "", []string{"gai-5", "gai-6"}, DeleteOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gai-6"}, report.NotFound)
+	assert.Empty(t, fake.assets)
+
+	assert.ErrorIs(t, DeleteAsset(url, "key", 5), ErrNotFound)
+	_, err = DeleteAssetTree(url, "key", 1, Locational, DeleteOptions{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestListAssets(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t)
+	fake.assets[1] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(1))), GlobalAssetIdentifier: "building", AssetType: "building", SiteId: *api.NewNullableString(common.Ptr("site-a"))}
+	fake.assets[2] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(2))), GlobalAssetIdentifier: "dev-1", AssetType: "device", Tags: []string{"outdoor"}, ParentLocationalAssetId: *api.NewNullableInt32(common.Ptr(int32(1))), SiteId: *api.NewNullableString(common.Ptr("site-a"))}
+	fake.assets[3] = api.Asset{Id: *api.NewNullableInt32(common.Ptr(int32(3))), GlobalAssetIdentifier: "dev-1", AssetType: "device", SiteId: *api.NewNullableString(common.Ptr("site-b"))}
+
+	assets, err := ListAssets(url, "key", AssetFilter{AssetType: "device"})
+	assert.NoError(t, err)
+	assert.Len(t, assets, 2)
+
+	assets, err = ListAssets(url, "key", AssetFilter{Tag: "outdoor"})
+	assert.NoError(t, err)
+	assert.Len(t, assets, 1)
+
+	assets, err = ListAssets(url, "key", AssetFilter{ParentID: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, assets, 1) {
+		assert.Equal(t, int32(2), assets[0].GetId())
+	}
+
+	asset, err := GetAssetByGAI(url, "key", "site-b", "dev-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), asset.GetId())
+	_, err = GetAssetByGAI(url, "key", "site-c", "dev-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = GetAssetByGAI(url, "key", "", "dev-1")
+	assert.ErrorIs(t, err, ErrAmbiguousGAI)
+	asset, err = GetAssetByGAI(url, "key", "", "building")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), asset.GetId())
+
+	count := 0
+	for _, err := range IterateAssets(context.Background(), url, "key", AssetFilter{}) {
+		assert.NoError(t, err)
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+}
+
+type testMode string
+
+type testDevice struct {
+	Temperature float64   `eliona:"temperature" subtype:"input"`
+	Count       int16     `eliona:"count" subtype:"input"`
+	Open        bool      `eliona:"open" subtype:"input"`
+	Mode        testMode  `eliona:"mode" subtype:"status"`
+	Serial      string    `eliona:"serial" subtype:"info"`
+	Installed   time.Time `eliona:"installed" subtype:"info"`
+	Readings    []int     `eliona:"readings" subtype:"input"`
+	Limit       *float32  `eliona:"limit" subtype:"property"`
+	Missing     string    `eliona:"missing" subtype:"info"`
+	Untagged    string
+}
+
+func TestGetAssetData(t *testing.T) {
+	fake, url := newFakeAssetsAPI(t)
+	fake.data = []api.Data{
+		{AssetId: 1, Subtype: api.SUBTYPE_INPUT, Data: map[string]any{"temperature": 21.5, "count": 3, "open": 1, "readings": []any{1, 2, 3}}},
+		{AssetId: 1, Subtype: api.SUBTYPE_STATUS, Data: map[string]any{"mode": "auto"}},
+		{AssetId: 1, Subtype: api.SUBTYPE_INFO, Data: map[string]any{"serial": 12345, "installed": "2024-03-01T12:00:00Z"}},
+		{AssetId: 1, Subtype: api.SUBTYPE_PROPERTY, Data: map[string]any{"limit": 30}},
+		{AssetId: 2, Subtype: api.SUBTYPE_INPUT, Data: map[string]any{"temperature": "warm", "count": 100000}},
+	}
+
+	device, err := GetAssetData[testDevice](url, "key", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, testDevice{
+		Temperature: 21.5,
+		Count:       3,
+		Open:        true,
+		Mode:        "auto",
+		Serial:      "12345",
+		Installed:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Readings:    []int{1, 2, 3},
+		Limit:       common.Ptr(float32(30)),
+	}, device)
+
+	_, err = GetAssetData[testDevice](url, "key", 2)
+	assert.ErrorContains(t, err, "attribute temperature of subtype input into field Temperature")
+	assert.ErrorContains(t, err, "100000 does not fit into int16")
+}
+
+type testBin struct {
+	Level   float64 `eliona:"level,unit=%,precision=1,min=0,max=100,type=level" subtype:"input" translation:"en=Fill level;de=Füllstand" pipeline:"mode=avg;rasters=M15,H1"`
+	Alarm   bool    `eliona:"alarm,type=device-status" subtype:"input"`
+	Serial  string  `eliona:"serial,filterable" subtype:"info"`
+	Comment string  `eliona:"comment,filterable"`
+}
+
+func TestGenerateAssetType(t *testing.T) {
+	assetType, err := GenerateAssetType[testBin](api.AssetType{Name: "bin", Custom: common.Ptr(true)})
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "bin.json")
+	assert.NoError(t, WriteAssetTypeFile(path, assetType))
+
+	read, err := common.UnmarshalFile[api.AssetType](path)
+	assert.NoError(t, err)
+	assert.Equal(t, "bin", read.Name)
+	if assert.Len(t, read.Attributes, 3) {
+		level := read.Attributes[0]
+		assert.Equal(t, "level", level.Name)
+		assert.Equal(t, api.SUBTYPE_INPUT, level.Subtype)
+		assert.Equal(t, "%", *level.Unit.Get())
+		assert.Equal(t, "level", *level.Type.Get())
+		assert.Equal(t, int64(1), *level.Precision.Get())
+		assert.Equal(t, 0.0, *level.Min.Get())
+		assert.Equal(t, 100.0, *level.Max.Get())
+		assert.Equal(t, "Füllstand", *level.Translation.Get().De)
+		assert.Equal(t, "avg", *level.AggregationMode.Get())
+		assert.Equal(t, []string{"M15", "H1"}, level.AggregationRasters)
+		assert.True(t, *read.Attributes[1].IsDigital.Get())
+		assert.Equal(t, api.SUBTYPE_INFO, read.Attributes[2].Subtype)
+	}
+
+	type invalid struct {
+		Level float64 `eliona:"level,precision=high" subtype:"input"`
+	}
+	_, err = GenerateAssetType[invalid](api.AssetType{Name: "invalid"})
+	assert.ErrorContains(t, err, `precision "high"`)
+}
+
+type testHailoBin struct {
+	Openings      int     `eliona:"openings" subtype:"input"`
+	BatteryLevel  float64 `eliona:"bat_level" subtype:"input"`
+	Volume        float64 `eliona:"volume" subtype:"input"`
+	Alarm         bool    `eliona:"alarm" subtype:"input"`
+	TotalOpenings int     `eliona:"totalopenings" subtype:"input"`
+	Fill          float64 `eliona:"fill_level" subtype:"input"`
+	Name          string  `eliona:"name,filterable"`
+}
+
+type testHailoBinInfo struct {
+	VolumePercent float64 `eliona:"volumepercent" subtype:"input"`
+	ExpPercent    float64 `eliona:"exp_percent" subtype:"status"`
+	RegDate       string  `eliona:"reg_date" subtype:"info"`
+	LastClean     string  `eliona:"lastclean" subtype:"input"`
+	Time          string  `eliona:"time" subtype:"input"`
+}
+
+func TestCheckAssetTypeFile(t *testing.T) {
+	report, err := CheckAssetTypeFile("test-asset-type.json", testHailoBin{}, &testHailoBinInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, []AttributeMismatch{{Attribute: "fill_level", Struct: "testHailoBin", Field: "Fill", Subtype: api.SUBTYPE_INPUT}}, report.Missing)
+	assert.Equal(t, []AttributeMismatch{{Attribute: "volume", Struct: "testHailoBin", Field: "Volume", Subtype: api.SUBTYPE_INPUT, TypeSubtype: api.SUBTYPE_INFO}}, report.WrongSubtype)
+	assert.Equal(t, []AttributeMismatch{{Attribute: "last_contact", TypeSubtype: api.SUBTYPE_INPUT}}, report.Unused)
+	assert.ErrorContains(t, report.Err(), "attribute volume of testHailoBin.Volume has subtype input instead of info")
+
+	_, err = CheckAssetTypeFile("test-asset-type.json", "not a struct")
+	assert.Error(t, err)
+}
+
+func TestParseTag(t *testing.T) {
+	type tagged struct {
+		Temperature float64 `eliona:"temperature,subtype=input,unit=°C,filterable"`
+		Setpoint    float64 `eliona:"setpoint,readonly" subtype:"output"`
+		Comment     string  `eliona:"comment,omitempty" subtype:"info"`
+		Ignored     string  `eliona:"-"`
+		Unknown     string  `eliona:"unknown,filterabel" subtype:"info"`
+		Conflict    string  `eliona:"conflict,subtype=info" subtype:"input"`
+		Invalid     string  `eliona:",unit" subtype:"data"`
+	}
+	fields := reflect.VisibleFields(reflect.TypeFor[tagged]())
+
+	tag, ok, err := ParseTag(fields[0])
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, ElionaTag{AttributeName: "temperature", Subtype: api.SUBTYPE_INPUT, Unit: "°C", Filterable: true}, tag)
+
+	tag, _, err = ParseTag(fields[1])
+	assert.NoError(t, err)
+	assert.True(t, tag.ReadOnly)
+
+	_, ok, err = ParseTag(fields[3])
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = ParseTag(fields[4])
+	assert.ErrorIs(t, err, ErrInvalidTag)
+	assert.ErrorContains(t, err, `unknown option "filterabel"`)
+	_, _, err = ParseTag(fields[5])
+	assert.ErrorContains(t, err, "subtype info conflicts with subtype tag input")
+	_, _, err = ParseTag(fields[6])
+	assert.ErrorContains(t, err, "missing attribute name, option unit needs a value, invalid subtype \"data\"")
+
+	split := SplitBySubtype(tagged{Temperature: 20, Setpoint: 21})
+	assert.Equal(t, map[api.DataSubtype]map[string]interface{}{api.SUBTYPE_INPUT: {"temperature": 20.0}}, split)
+}
+
+type testLocation struct {
+	Building string `eliona:"building" subtype:"info"`
+	Floor    *int   `eliona:"floor" subtype:"info"`
+}
+
+type testBattery struct {
+	Level   int     `eliona:"level"`
+	Voltage float64 `eliona:"voltage,omitempty"`
+}
+
+type testNestedDevice struct {
+	testLocation
+	*testBattery `eliona:",prefix=battery_" subtype:"status"`
+	Room         *testLocation `eliona:"room" subtype:"info"`
+	Secondary    *testBattery  `eliona:",prefix=secondary_" subtype:"status"`
+	Temperature  float64       `eliona:"temperature" subtype:"input"`
+}
+
+func TestSplitBySubtypeNested(t *testing.T) {
+	floor := 3
+	device := testNestedDevice{
+		testLocation: testLocation{Building: "HQ", Floor: &floor},
+		testBattery:  &testBattery{Level: 80},
+		Room:         &testLocation{Building: "HQ"},
+		Temperature:  21.5,
+	}
+	split, err := splitBySubtype(&device)
+	assert.NoError(t, err)
+	assert.Equal(t, map[api.DataSubtype]map[string]interface{}{
+		api.SUBTYPE_INFO:   {"building": "HQ", "floor": 3, "room": testLocation{Building: "HQ"}},
+		api.SUBTYPE_STATUS: {"battery_level": 80},
+		api.SUBTYPE_INPUT:  {"temperature": 21.5},
+	}, split)
+
+	type invalid struct {
+		Level int `eliona:"level,prefix=level_" subtype:"status"`
+	}
+	_, err = splitBySubtype(invalid{})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+	_, err = splitBySubtype(42)
+	assert.Error(t, err)
+
+	type node struct {
+		Value int   `eliona:"value" subtype:"input"`
+		Next  *node `eliona:",prefix=next_"`
+	}
+	_, err = GenerateAssetType[node](api.AssetType{Name: "node"})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+type roundTripInfo struct {
+	Serial string `eliona:"serial" subtype:"info"`
+}
+
+type roundTripLocation struct {
+	Room  string `eliona:"room"`
+	Floor int    `eliona:"floor"`
+}
+
+type roundTripBattery struct {
+	Level float64 `eliona:"level" subtype:"status"`
+}
+
+type roundTripDevice struct {
+	roundTripInfo
+	Loc     roundTripLocation `eliona:",prefix=loc_" subtype:"info"`
+	Battery *roundTripBattery `eliona:",prefix=battery_"`
+	Spare   *roundTripBattery `eliona:",prefix=spare_"`
+	Temp    float64           `eliona:"temp" subtype:"input"`
+}
+
+func TestNestedStructRoundTrip(t *testing.T) {
+	device := roundTripDevice{
+		roundTripInfo: roundTripInfo{Serial: "A-1"},
+		Loc:           roundTripLocation{Room: "1.01", Floor: 1},
+		Battery:       &roundTripBattery{Level: 80},
+		Temp:          21.5,
+	}
+	split, err := splitBySubtype(device)
+	assert.NoError(t, err)
+	assert.Equal(t, map[api.DataSubtype]map[string]interface{}{
+		api.SUBTYPE_INFO:   {"serial": "A-1", "loc_room": "1.01", "loc_floor": 1},
+		api.SUBTYPE_STATUS: {"battery_level": 80.0},
+		api.SUBTYPE_INPUT:  {"temp": 21.5},
+	}, split)
+
+	assetType, err := GenerateAssetType[roundTripDevice](api.AssetType{Name: "device"})
+	assert.NoError(t, err)
+	var names []string
+	for _, attribute := range assetType.Attributes {
+		names = append(names, attribute.Name)
+	}
+	assert.Equal(t, []string{"serial", "loc_room", "loc_floor", "battery_level", "spare_level", "temp"}, names)
+
+	report, err := CheckAssetType(assetType, roundTripDevice{})
+	assert.NoError(t, err)
+	assert.NoError(t, report.Err())
+	assetType.Attributes = assetType.Attributes[2:]
+	report, err = CheckAssetType(assetType, roundTripDevice{})
+	assert.NoError(t, err)
+	if assert.Len(t, report.Missing, 2) {
+		assert.Equal(t, "roundTripInfo.Serial", report.Missing[0].Field)
+		assert.Equal(t, "Loc.Room", report.Missing[1].Field)
+	}
+
+	// The data is decoded after a JSON round trip, as read from Eliona.
+	var datas []api.Data
+	for subtype, data := range split {
+		content, err := json.Marshal(data)
+		assert.NoError(t, err)
+		var decoded map[string]interface{}
+		assert.NoError(t, json.Unmarshal(content, &decoded))
+		datas = append(datas, api.Data{Subtype: subtype, Data: decoded})
+	}
+	decoded, err := DecodeAssetData[roundTripDevice](datas)
+	assert.NoError(t, err)
+	assert.Equal(t, device, decoded)
+}
@@ -0,0 +1,131 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+	"github.com/eliona-smart-building-assistant/go-eliona/v2/client"
+)
+
+// DefaultChunkSize is the number of items sent in one request by the bulk upserts, if ChunkOptions
+// does not define another size.
+var DefaultChunkSize = 500
+
+// ChunkOptions configures chunked bulk upserts.
+type ChunkOptions struct {
+	// ChunkSize is the maximum number of items per request. If 0, DefaultChunkSize is used.
+	ChunkSize int
+	// Bisect splits failed chunks in halves and retries them, until the items causing the failure are
+	// isolated. This is only done for errors which single items can cause, i.e. validation and
+	// conflict errors. Server errors are not bisected to avoid flooding a failing server.
+	Bisect bool
+}
+
+// FailedItem is an item which could not be upserted, together with the reason.
+type FailedItem[T any] struct {
+	Item T
+	Err  error
+}
+
+// BulkResult reports the outcome of a chunked bulk upsert. Without bisection, all items of a failed
+// chunk are reported as failed with the error of the chunk.
+type BulkResult[T any] struct {
+	Succeeded []T
+	Failed    []FailedItem[T]
+}
+
+// Err returns nil if all items succeeded. Otherwise, it returns an error wrapping the error of the
+// first failed item.
+func (r BulkResult[T]) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d items failed: %w", len(r.Failed), len(r.Failed)+len(r.Succeeded), r.Failed[0].Err)
+}
+
+// UpsertAssetsBulkGAIChunked works like UpsertAssetsBulkGAI, but sends the assets in chunks and reports
+// which assets failed. Succeeded contains the upserted assets including their IDs. Parents must come
+// before their children, as for UpsertAssetsBulkGAI.
+func UpsertAssetsBulkGAIChunked(apiEndpoint string, apiKey string, assets []api.Asset, options ChunkOptions) BulkResult[api.Asset] {
+	return UpsertAssetsBulkGAIChunkedContext(context.Background(), apiEndpoint, apiKey, assets, options)
+}
+
+// UpsertAssetsBulkGAIChunkedContext works like UpsertAssetsBulkGAIChunked using the given context.
+func UpsertAssetsBulkGAIChunkedContext(ctx context.Context, apiEndpoint string, apiKey string, assets []api.Asset, options ChunkOptions) BulkResult[api.Asset] {
+	return upsertChunked(ctx, assets, options, func(chunk []api.Asset) ([]api.Asset, error) {
+		return putBulkAssets(ctx, apiEndpoint, apiKey, chunk)
+	})
+}
+
+// UpsertDataBulkChunked works like UpsertDataBulk, but sends the data in chunks and reports which data
+// failed.
+func UpsertDataBulkChunked(apiEndpoint string, apiKey string, datas []api.Data, options ChunkOptions) BulkResult[api.Data] {
+	return UpsertDataBulkChunkedContext(context.Background(), apiEndpoint, apiKey, datas, options)
+}
+
+// UpsertDataBulkChunkedContext works like UpsertDataBulkChunked using the given context.
+func UpsertDataBulkChunkedContext(ctx context.Context, apiEndpoint string, apiKey string, datas []api.Data, options ChunkOptions) BulkResult[api.Data] {
+	return upsertChunked(ctx, datas, options, func(chunk []api.Data) ([]api.Data, error) {
+		return chunk, putBulkData(ctx, apiEndpoint, apiKey, chunk)
+	})
+}
+
+// upsertChunked calls upsert for each chunk of the items and collects the result. After the context
+// is canceled, the remaining items fail without being sent.
+func upsertChunked[T any](ctx context.Context, items []T, options ChunkOptions, upsert func([]T) ([]T, error)) BulkResult[T] {
+	size := options.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	var result BulkResult[T]
+	var upsertChunk func(chunk []T)
+	upsertChunk = func(chunk []T) {
+		if err := ctx.Err(); err != nil {
+			result.fail(chunk, err)
+			return
+		}
+		succeeded, err := upsert(chunk)
+		if err == nil {
+			result.Succeeded = append(result.Succeeded, succeeded...)
+			return
+		}
+		if !options.Bisect || len(chunk) == 1 || !causedByItems(err) {
+			result.fail(chunk, err)
+			return
+		}
+		upsertChunk(chunk[:len(chunk)/2])
+		upsertChunk(chunk[len(chunk)/2:])
+	}
+	for start := 0; start < len(items); start += size {
+		upsertChunk(items[start:min(start+size, len(items))])
+	}
+	return result
+}
+
+func (r *BulkResult[T]) fail(items []T, err error) {
+	for _, item := range items {
+		r.Failed = append(r.Failed, FailedItem[T]{Item: item, Err: err})
+	}
+}
+
+// causedByItems returns true if the error could be caused by single items of a request.
+func causedByItems(err error) bool {
+	return errors.Is(err, client.ErrValidation) || errors.Is(err, client.ErrConflict)
+}
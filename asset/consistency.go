@@ -0,0 +1,123 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+)
+
+// AttributeMismatch describes an attribute which differs between a struct and an asset type. Struct and
+// Field are empty for unused attributes, TypeSubtype is empty for missing attributes.
+type AttributeMismatch struct {
+	Attribute   string
+	Struct      string
+	Field       string
+	Subtype     api.DataSubtype
+	TypeSubtype api.DataSubtype
+}
+
+// ConsistencyReport lists the differences between tagged structs and an asset type: attributes written by
+// the structs but missing in the asset type, attributes with a different subtype and attributes of the
+// asset type not used by any struct.
+type ConsistencyReport struct {
+	AssetType    string
+	Missing      []AttributeMismatch
+	WrongSubtype []AttributeMismatch
+	Unused       []AttributeMismatch
+}
+
+// Err returns nil if the structs and the asset type are consistent, otherwise an error listing all
+// differences. Use it in unit tests, e.g. assert.NoError(t, report.Err()).
+func (r ConsistencyReport) Err() error {
+	var problems []string
+	for _, m := range r.Missing {
+		problems = append(problems, fmt.Sprintf("attribute %s of %s.%s is missing", m.Attribute, m.Struct, m.Field))
+	}
+	for _, m := range r.WrongSubtype {
+		problems = append(problems, fmt.Sprintf("attribute %s of %s.%s has subtype %s instead of %s", m.Attribute, m.Struct, m.Field, m.Subtype, m.TypeSubtype))
+	}
+	for _, m := range r.Unused {
+		problems = append(problems, fmt.Sprintf("attribute %s (%s) is not used", m.Attribute, m.TypeSubtype))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("asset type %s is inconsistent: %s", r.AssetType, strings.Join(problems, "; "))
+}
+
+// CheckAssetTypeFile loads the asset type file and compares it with the given structs, see CheckAssetType.
+func CheckAssetTypeFile(path string, structs ...any) (ConsistencyReport, error) {
+	assetType, err := common.UnmarshalFile[api.AssetType](path)
+	if err != nil {
+		return ConsistencyReport{}, fmt.Errorf("unmarshalling file %s: %w", path, err)
+	}
+	return CheckAssetType(assetType, structs...)
+}
+
+// CheckAssetType compares the attributes of the asset type with the fields of the structs having
+// `eliona` and `subtype` tags; fields of embedded and nested structs count as WalkTaggedFields walks
+// them. Pass struct values or pointers, e.g. CheckAssetType(assetType, Bin{}, BinInfo{}). An error is
+// returned for invalid tags or arguments which are no structs.
+func CheckAssetType(assetType api.AssetType, structs ...any) (ConsistencyReport, error) {
+	report := ConsistencyReport{AssetType: assetType.Name}
+	attributes := make(map[string]api.DataSubtype, len(assetType.Attributes))
+	for _, attribute := range assetType.Attributes {
+		attributes[attribute.Name] = attribute.Subtype
+	}
+
+	used := make(map[string]bool)
+	for _, s := range structs {
+		structType := reflect.TypeOf(s)
+		for structType != nil && structType.Kind() == reflect.Pointer {
+			structType = structType.Elem()
+		}
+		if structType == nil || structType.Kind() != reflect.Struct {
+			return report, fmt.Errorf("checking asset type %s: %T is not a struct", assetType.Name, s)
+		}
+		fields, err := taggedFields(structType)
+		if err != nil {
+			return report, fmt.Errorf("checking asset type %s: %w", assetType.Name, err)
+		}
+		for _, field := range fields {
+			tag := field.Tag
+			if tag.Subtype == "" {
+				continue
+			}
+			mismatch := AttributeMismatch{Attribute: tag.AttributeName, Struct: structType.Name(), Field: field.Name, Subtype: tag.Subtype}
+			typeSubtype, exists := attributes[tag.AttributeName]
+			switch {
+			case !exists:
+				report.Missing = append(report.Missing, mismatch)
+			case typeSubtype != tag.Subtype:
+				mismatch.TypeSubtype = typeSubtype
+				report.WrongSubtype = append(report.WrongSubtype, mismatch)
+			}
+			used[tag.AttributeName] = true
+		}
+	}
+
+	for _, attribute := range assetType.Attributes {
+		if !used[attribute.Name] {
+			report.Unused = append(report.Unused, AttributeMismatch{Attribute: attribute.Name, TypeSubtype: attribute.Subtype})
+		}
+	}
+	return report, nil
+}
@@ -16,6 +16,7 @@
 package asset
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 
@@ -27,10 +28,16 @@ import (
 // UpsertData inserts or updates the given asset data. If the data with the specified subtype does not exists, it will be created.
 // Otherwise, the timestamp and the data are updated.
 func UpsertData(apiEndpoint string, apiKey string, data api.Data) error {
-	_, err := client.NewClient(apiEndpoint).DataAPI.
-		PutData(client.AuthenticationContext(apiKey)).
+	return UpsertDataContext(context.Background(), apiEndpoint, apiKey, data)
+}
+
+// UpsertDataContext works like UpsertData using the given context.
+func UpsertDataContext(ctx context.Context, apiEndpoint string, apiKey string, data api.Data) error {
+	res, err := client.NewClientContext(ctx, apiEndpoint).DataAPI.
+		PutData(client.AuthenticationContextWrap(ctx, apiKey)).
 		Data(data).
 		Execute()
+	err = client.NewAPIError("PutData", res, err)
 	if err != nil {
 		tools.LogError(fmt.Errorf("upserting data for asset %v: %w", data.AssetId, err))
 	}
@@ -40,10 +47,21 @@ func UpsertData(apiEndpoint string, apiKey string, data api.Data) error {
 // UpsertDataBulk inserts or updates the given asset data. If the data with the specified subtype does not exists, it will be created.
 // Otherwise, the timestamp and the data are updated.
 func UpsertDataBulk(apiEndpoint string, apiKey string, datas []api.Data) error {
-	_, err := client.NewClient(apiEndpoint).DataAPI.
-		PutBulkData(client.AuthenticationContext(apiKey)).
+	return UpsertDataBulkContext(context.Background(), apiEndpoint, apiKey, datas)
+}
+
+// UpsertDataBulkContext works like UpsertDataBulk using the given context. The data is sent in chunks of
+// DefaultChunkSize; use UpsertDataBulkChunkedContext to find out which data failed.
+func UpsertDataBulkContext(ctx context.Context, apiEndpoint string, apiKey string, datas []api.Data) error {
+	return UpsertDataBulkChunkedContext(ctx, apiEndpoint, apiKey, datas, ChunkOptions{}).Err()
+}
+
+func putBulkData(ctx context.Context, apiEndpoint string, apiKey string, datas []api.Data) error {
+	res, err := client.NewClientContext(ctx, apiEndpoint).DataAPI.
+		PutBulkData(client.AuthenticationContextWrap(ctx, apiKey)).
 		Data(datas).
 		Execute()
+	err = client.NewAPIError("PutBulkData", res, err)
 	if err != nil {
 		tools.LogError(fmt.Errorf("upserting data bulk: %w", err))
 	}
@@ -51,30 +69,49 @@ func UpsertDataBulk(apiEndpoint string, apiKey string, datas []api.Data) error {
 }
 
 // UpsertDataIfAssetExists upserts the data if the eliona id exists. Otherwise, the upsert is ignored.
+// The existence is cached in DefaultExistenceCache.
 func UpsertDataIfAssetExists(apiEndpoint string, apiKey string, data api.Data) error {
-	exists, err := ExistAsset(apiEndpoint, apiKey, data.AssetId)
+	return UpsertDataIfAssetExistsContext(context.Background(), apiEndpoint, apiKey, data)
+}
+
+// UpsertDataIfAssetExistsContext works like UpsertDataIfAssetExists using the given context.
+func UpsertDataIfAssetExistsContext(ctx context.Context, apiEndpoint string, apiKey string, data api.Data) error {
+	exists, err := DefaultExistenceCache.Exists(ctx, apiEndpoint, apiKey, data.AssetId)
 	if err != nil {
 		return fmt.Errorf("checking if asset %v exists: %w", data.AssetId, err)
 	}
 	if exists {
-		return UpsertData(apiEndpoint, apiKey, data)
+		return UpsertDataContext(ctx, apiEndpoint, apiKey, data)
 	}
 	return nil
 }
 
 // UpsertDataBulkIfAssetExists upserts the data if the eliona id exists. Otherwise, the upsert is ignored.
+// Each asset is checked only once per call and the results are cached in DefaultExistenceCache.
 func UpsertDataBulkIfAssetExists(apiEndpoint string, apiKey string, datas []api.Data) error {
+	return UpsertDataBulkIfAssetExistsContext(context.Background(), apiEndpoint, apiKey, datas)
+}
+
+// UpsertDataBulkIfAssetExistsContext works like UpsertDataBulkIfAssetExists using the given context.
+func UpsertDataBulkIfAssetExistsContext(ctx context.Context, apiEndpoint string, apiKey string, datas []api.Data) error {
+	assetIds := make([]int32, 0, len(datas))
+	for _, data := range datas {
+		assetIds = append(assetIds, data.AssetId)
+	}
+	existing, err := DefaultExistenceCache.existMany(ctx, apiEndpoint, apiKey, assetIds)
+	if err != nil {
+		return err
+	}
 	upsertDatas := make([]api.Data, 0, len(datas))
 	for _, data := range datas {
-		exists, err := ExistAsset(apiEndpoint, apiKey, data.AssetId)
-		if err != nil {
-			return fmt.Errorf("checking if asset %v exists: %w", data.AssetId, err)
-		}
-		if exists {
+		if existing[data.AssetId] {
 			upsertDatas = append(upsertDatas, data)
 		}
 	}
-	return UpsertDataBulk(apiEndpoint, apiKey, upsertDatas)
+	if len(upsertDatas) == 0 {
+		return nil
+	}
+	return UpsertDataBulkContext(ctx, apiEndpoint, apiKey, upsertDatas)
 }
 
 type Data struct {
@@ -87,9 +124,17 @@ type Data struct {
 // UpsertAssetDataIfAssetExists upserts the data in any struct having `eliona` field tags.
 // If the eliona ID does not exist, the upsert is ignored.
 func UpsertAssetDataIfAssetExists(apiEndpoint string, apiKey string, data Data) error {
-	subtypes := SplitBySubtype(data.Data)
+	return UpsertAssetDataIfAssetExistsContext(context.Background(), apiEndpoint, apiKey, data)
+}
+
+// UpsertAssetDataIfAssetExistsContext works like UpsertAssetDataIfAssetExists using the given context.
+func UpsertAssetDataIfAssetExistsContext(ctx context.Context, apiEndpoint string, apiKey string, data Data) error {
+	subtypes, err := splitBySubtype(data.Data)
+	if err != nil {
+		return fmt.Errorf("splitting data of asset %v by subtype: %w", data.AssetId, err)
+	}
 	for subtype, subData := range subtypes {
-		if err := UpsertData(apiEndpoint, apiKey, api.Data{
+		if err := UpsertDataContext(ctx, apiEndpoint, apiKey, api.Data{
 			AssetId:         data.AssetId,
 			Subtype:         subtype,
 			Timestamp:       data.Timestamp,
@@ -103,52 +148,47 @@ func UpsertAssetDataIfAssetExists(apiEndpoint string, apiKey string, data Data)
 	return nil
 }
 
+// SplitBySubtype groups the values of the fields having `eliona` tags with a subtype by subtype, including
+// the fields of embedded and nested structs as described for WalkTaggedFields. Read-only fields and empty
+// fields tagged with omitempty are left out. Fields with invalid tags are logged and skipped.
 func SplitBySubtype(data any) map[api.DataSubtype]map[string]interface{} {
-	value := reflect.ValueOf(data)
-	if value.Kind() == reflect.Ptr {
-		value = value.Elem()
-	}
-	valueType := reflect.TypeOf(data)
-	if valueType.Kind() == reflect.Ptr {
-		valueType = valueType.Elem()
+	result, err := splitBySubtype(data)
+	if err != nil {
+		tools.LogError(fmt.Errorf("splitting data by subtype: %w", err))
 	}
+	return result
+}
 
+// splitBySubtype works like SplitBySubtype, but returns the errors of invalid tags.
+func splitBySubtype(data any) (map[api.DataSubtype]map[string]interface{}, error) {
 	result := make(map[api.DataSubtype]map[string]interface{})
-
-	for i := 0; i < valueType.NumField(); i++ {
-		field := valueType.Field(i)
-
-		if field.PkgPath != "" {
-			// Skip unexported fields.
-			continue
+	err := WalkTaggedFields(data, func(tag ElionaTag, value reflect.Value) {
+		if tag.Subtype == "" || tag.ReadOnly {
+			return
 		}
-		fieldValue := value.Field(i).Interface()
-
-		tag, ok := ParseElionaTag(field)
-		if !ok {
-			// Skip fields without tag.
-			continue
+		if tag.OmitEmpty && value.IsZero() {
+			return
 		}
-
-		if tag.Subtype == "" {
-			continue
-		}
-
 		if _, ok := result[tag.Subtype]; !ok {
 			result[tag.Subtype] = make(map[string]interface{})
 		}
-		result[tag.Subtype][tag.AttributeName] = fieldValue
-	}
-
-	return result
+		result[tag.Subtype][tag.AttributeName] = value.Interface()
+	})
+	return result, err
 }
 
 func GetData(apiEndpoint string, apiKey string, assetID int32, subtype string) ([]api.Data, error) {
-	data, _, err := client.NewClient(apiEndpoint).DataAPI.
-		GetData(client.AuthenticationContext(apiKey)).
+	return GetDataContext(context.Background(), apiEndpoint, apiKey, assetID, subtype)
+}
+
+// GetDataContext works like GetData using the given context.
+func GetDataContext(ctx context.Context, apiEndpoint string, apiKey string, assetID int32, subtype string) ([]api.Data, error) {
+	data, res, err := client.NewClientContext(ctx, apiEndpoint).DataAPI.
+		GetData(client.AuthenticationContextWrap(ctx, apiKey)).
 		AssetId(assetID).
 		DataSubtype(subtype).
 		Execute()
+	err = client.NewAPIError("GetData", res, err)
 	if err != nil {
 		err = fmt.Errorf("getting data for asset %v subtype %v: %w", assetID, subtype, err)
 		tools.LogError(err)
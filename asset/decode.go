@@ -0,0 +1,227 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+	"github.com/eliona-smart-building-assistant/go-eliona-api-client/v3/tools"
+	"github.com/eliona-smart-building-assistant/go-eliona/v2/client"
+)
+
+// GetAssetData reads the data of all subtypes of the asset into a struct having `eliona` and `subtype`
+// field tags, as written by UpsertAssetDataIfAssetExists. Attributes missing in the data leave the
+// field unchanged.
+func GetAssetData[T any](apiEndpoint string, apiKey string, assetID int32) (T, error) {
+	return GetAssetDataContext[T](context.Background(), apiEndpoint, apiKey, assetID)
+}
+
+// GetAssetDataContext works like GetAssetData using the given context.
+func GetAssetDataContext[T any](ctx context.Context, apiEndpoint string, apiKey string, assetID int32) (T, error) {
+	var result T
+	datas, res, err := client.NewClientContext(ctx, apiEndpoint).DataAPI.
+		GetData(client.AuthenticationContextWrap(ctx, apiKey)).
+		AssetId(assetID).
+		Execute()
+	err = client.NewAPIError("GetData", res, err)
+	if err != nil {
+		err = fmt.Errorf("getting data for asset %v: %w", assetID, err)
+		tools.LogError(err)
+		return result, err
+	}
+	return DecodeAssetData[T](datas)
+}
+
+// DecodeAssetData merges the data of the subtypes into a struct having `eliona` and `subtype` field
+// tags. Embedded and nested structs are mapped to attributes the way WalkTaggedFields walks them, and
+// nil pointers to nested structs are allocated if they receive data. Values are converted to the field
+// types: numbers, booleans, strings, time values (RFC 3339 strings or Unix seconds), slices and pointers
+// of them. Other types are decoded via JSON. All values which cannot be converted are reported in the
+// returned error.
+func DecodeAssetData[T any](datas []api.Data) (T, error) {
+	var result T
+	value := reflect.ValueOf(&result).Elem()
+	if value.Kind() != reflect.Struct {
+		return result, fmt.Errorf("decoding asset data: %T is not a struct", result)
+	}
+	bySubtype := make(map[api.DataSubtype]map[string]interface{})
+	for _, data := range datas {
+		bySubtype[data.Subtype] = data.Data
+	}
+
+	var errs []error
+	fields, err := taggedFields(value.Type())
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, field := range fields {
+		tag := field.Tag
+		if tag.Subtype == "" {
+			continue
+		}
+		raw, ok := bySubtype[tag.Subtype][tag.AttributeName]
+		if !ok || raw == nil {
+			continue
+		}
+		converted, err := convertValue(raw, field.Field.Type)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("attribute %s of subtype %s into field %s: %w", tag.AttributeName, tag.Subtype, field.Name, err))
+			continue
+		}
+		target, ok := fieldByIndex(value, field.Index, true)
+		if !ok || !target.CanSet() {
+			// Fields of unexported embedded structs cannot be set.
+			continue
+		}
+		target.Set(converted)
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("decoding asset data: %w", errors.Join(errs...))
+	}
+	return result, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// convertValue converts a value decoded from JSON to the target type.
+func convertValue(raw any, target reflect.Type) (reflect.Value, error) {
+	rawValue := reflect.ValueOf(raw)
+	if rawValue.Type().AssignableTo(target) {
+		return rawValue, nil
+	}
+	if rawValue.Kind() == target.Kind() && rawValue.Kind() != reflect.Slice && rawValue.Kind() != reflect.Map {
+		// Named types like `type Mode string`
+		return rawValue.Convert(target), nil
+	}
+	if target == timeType {
+		return convertTime(raw)
+	}
+	switch target.Kind() {
+	case reflect.Pointer:
+		elem, err := convertValue(raw, target.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		pointer := reflect.New(target.Elem())
+		pointer.Elem().Set(elem)
+		return pointer, nil
+	case reflect.String:
+		switch raw.(type) {
+		case float64, bool, json.Number:
+			return reflect.ValueOf(fmt.Sprint(raw)).Convert(target), nil
+		}
+	case reflect.Bool:
+		switch v := raw.(type) {
+		case float64:
+			if v == 0 || v == 1 {
+				return reflect.ValueOf(v == 1).Convert(target), nil
+			}
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return reflect.ValueOf(b).Convert(target), nil
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f, ok := toFloat(raw); ok {
+			result := reflect.New(target).Elem()
+			if f != math.Trunc(f) || overflows(result, f) {
+				return reflect.Value{}, fmt.Errorf("%v does not fit into %s", raw, target)
+			}
+			if result.CanInt() {
+				result.SetInt(int64(f))
+			} else {
+				result.SetUint(uint64(f))
+			}
+			return result, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := toFloat(raw); ok {
+			return reflect.ValueOf(f).Convert(target), nil
+		}
+	case reflect.Slice:
+		if items, ok := raw.([]any); ok {
+			result := reflect.MakeSlice(target, len(items), len(items))
+			for i, item := range items {
+				if item == nil {
+					continue
+				}
+				converted, err := convertValue(item, target.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("item %d: %w", i, err)
+				}
+				result.Index(i).Set(converted)
+			}
+			return result, nil
+		}
+	case reflect.Map, reflect.Struct, reflect.Array:
+		encoded, err := json.Marshal(raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result := reflect.New(target)
+		if err := json.Unmarshal(encoded, result.Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf("cannot convert %T to %s: %w", raw, target, err)
+		}
+		return result.Elem(), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot convert %T %v to %s", raw, raw, target)
+}
+
+func convertTime(raw any) (reflect.Value, error) {
+	switch v := raw.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("cannot convert %q to time: %w", v, err)
+		}
+		return reflect.ValueOf(t), nil
+	case float64:
+		seconds, fraction := math.Modf(v)
+		return reflect.ValueOf(time.Unix(int64(seconds), int64(fraction*1e9))), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot convert %T %v to time", raw, raw)
+}
+
+// toFloat returns numbers and numeric strings as float64.
+func toFloat(raw any) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func overflows(value reflect.Value, f float64) bool {
+	if value.CanInt() {
+		return f < math.MinInt64 || f >= math.MaxInt64 || value.OverflowInt(int64(f))
+	}
+	return f < 0 || f >= math.MaxUint64 || value.OverflowUint(uint64(f))
+}
@@ -0,0 +1,227 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+	"github.com/eliona-smart-building-assistant/go-eliona-api-client/v3/tools"
+	"github.com/eliona-smart-building-assistant/go-eliona/v2/client"
+)
+
+// ErrDeleteLimitExceeded is returned if a delete would remove more assets than allowed. Nothing is
+// deleted in this case.
+var ErrDeleteLimitExceeded = errors.New("delete limit exceeded")
+
+// DefaultDeleteLimit is the maximum number of assets deleted per call, if DeleteOptions defines no
+// other limit.
+var DefaultDeleteLimit = 100
+
+// DeleteOptions configures DeleteAssetsByGAI and DeleteAssetTree.
+type DeleteOptions struct {
+	// DryRun determines the assets to delete without deleting them.
+	DryRun bool
+	// MaxAssets is the maximum number of assets deleted by one call. If 0, DefaultDeleteLimit is used.
+	// A negative value disables the limit.
+	MaxAssets int
+}
+
+// DeleteReport lists the assets to be deleted in the order of deletion. Deleted lists the assets actually
+// deleted; it is empty in a dry run and, if a deletion fails, holds the assets deleted before the failure.
+// NotFound lists the requested GAIs which do not exist.
+type DeleteReport struct {
+	Assets   []api.Asset
+	Deleted  []api.Asset
+	NotFound []string
+}
+
+// Hierarchy selects the locational or the functional asset tree.
+type Hierarchy string
+
+const (
+	Locational Hierarchy = "locational"
+	Functional Hierarchy = "functional"
+)
+
+// DeleteAsset deletes the asset with the given id. It returns ErrNotFound if the asset does not exist.
+func DeleteAsset(apiEndpoint string, apiKey string, assetId int32) error {
+	return DeleteAssetContext(context.Background(), apiEndpoint, apiKey, assetId)
+}
+
+// DeleteAssetContext works like DeleteAsset using the given context.
+func DeleteAssetContext(ctx context.Context, apiEndpoint string, apiKey string, assetId int32) error {
+	res, err := client.NewClientContext(ctx, apiEndpoint).AssetsAPI.
+		DeleteAssetById(client.AuthenticationContextWrap(ctx, apiKey), assetId).
+		Execute()
+	err = client.NewAPIError("DeleteAssetById", res, err)
+	if err != nil {
+		tools.LogError(fmt.Errorf("deleting asset %v: %w", assetId, err))
+		return err
+	}
+	DefaultExistenceCache.Set(cacheEndpoint(ctx, apiEndpoint), assetId, false)
+	return nil
+}
+
+// DeleteAssetsByGAI deletes the assets with the given GAIs in the site. If the site ID is empty, assets
+// of all sites are matched by GAI only. Otherwise, only assets of exactly this site are deleted. GAIs
+// which do not exist are reported as not found.
+func DeleteAssetsByGAI(apiEndpoint string, apiKey string, siteID string, gais []string, options DeleteOptions) (DeleteReport, error) {
+	return DeleteAssetsByGAIContext(context.Background(), apiEndpoint, apiKey, siteID, gais, options)
+}
+
+// DeleteAssetsByGAIContext works like DeleteAssetsByGAI using the given context.
+func DeleteAssetsByGAIContext(ctx context.Context, apiEndpoint string, apiKey string, siteID string, gais []string, options DeleteOptions) (DeleteReport, error) {
+	var report DeleteReport
+	assets, err := listAssets(ctx, apiEndpoint, apiKey, "", "")
+	if err != nil {
+		return report, err
+	}
+	byGAI := make(map[string][]api.Asset)
+	for _, asset := range assets {
+		if siteID == "" || stringValue(asset.SiteId) == siteID {
+			byGAI[asset.GlobalAssetIdentifier] = append(byGAI[asset.GlobalAssetIdentifier], asset)
+		}
+	}
+	var toDelete []api.Asset
+	for _, gai := range gais {
+		found, ok := byGAI[gai]
+		if !ok {
+			report.NotFound = append(report.NotFound, gai)
+			continue
+		}
+		toDelete = append(toDelete, found...)
+		delete(byGAI, gai)
+	}
+	report.Assets = sortChildrenFirst(toDelete)
+	report.Deleted, err = deleteAssets(ctx, apiEndpoint, apiKey, report.Assets, options)
+	return report, err
+}
+
+// DeleteAssetTree deletes the asset with the given id and all its descendants in the locational or
+// functional hierarchy. Children are deleted before their parents.
+func DeleteAssetTree(apiEndpoint string, apiKey string, rootId int32, hierarchy Hierarchy, options DeleteOptions) (DeleteReport, error) {
+	return DeleteAssetTreeContext(context.Background(), apiEndpoint, apiKey, rootId, hierarchy, options)
+}
+
+// DeleteAssetTreeContext works like DeleteAssetTree using the given context.
+func DeleteAssetTreeContext(ctx context.Context, apiEndpoint string, apiKey string, rootId int32, hierarchy Hierarchy, options DeleteOptions) (DeleteReport, error) {
+	var report DeleteReport
+	if hierarchy != Locational && hierarchy != Functional {
+		return report, fmt.Errorf("unknown hierarchy %q", hierarchy)
+	}
+	assets, err := listAssets(ctx, apiEndpoint, apiKey, "", "")
+	if err != nil {
+		return report, err
+	}
+	children := make(map[int32][]api.Asset)
+	var root *api.Asset
+	for i, asset := range assets {
+		if asset.GetId() == rootId {
+			root = &assets[i]
+		}
+		parent := asset.ParentLocationalAssetId
+		if hierarchy == Functional {
+			parent = asset.ParentFunctionalAssetId
+		}
+		if parent.Get() != nil {
+			children[*parent.Get()] = append(children[*parent.Get()], asset)
+		}
+	}
+	if root == nil {
+		return report, fmt.Errorf("deleting asset tree %v: %w", rootId, ErrNotFound)
+	}
+
+	// Collect the tree breadth-first and delete it in reverse order, so children go before parents.
+	tree := []api.Asset{*root}
+	visited := map[int32]bool{rootId: true}
+	for i := 0; i < len(tree); i++ {
+		for _, child := range children[tree[i].GetId()] {
+			if !visited[child.GetId()] {
+				visited[child.GetId()] = true
+				tree = append(tree, child)
+			}
+		}
+	}
+	for i := len(tree) - 1; i >= 0; i-- {
+		report.Assets = append(report.Assets, tree[i])
+	}
+	report.Deleted, err = deleteAssets(ctx, apiEndpoint, apiKey, report.Assets, options)
+	return report, err
+}
+
+// deleteAssets deletes the assets in the given order after checking the limit. It stops at the first
+// failure and returns the assets deleted so far. Assets which are already gone count as deleted.
+func deleteAssets(ctx context.Context, apiEndpoint string, apiKey string, assets []api.Asset, options DeleteOptions) ([]api.Asset, error) {
+	if err := checkDeleteLimit(len(assets), options); err != nil {
+		return nil, err
+	}
+	if options.DryRun {
+		return nil, nil
+	}
+	for i, asset := range assets {
+		if err := DeleteAssetContext(ctx, apiEndpoint, apiKey, asset.GetId()); err != nil && !errors.Is(err, ErrNotFound) {
+			return assets[:i], fmt.Errorf("deleting asset %s: %w", asset.GlobalAssetIdentifier, err)
+		}
+	}
+	return assets, nil
+}
+
+// checkDeleteLimit returns ErrDeleteLimitExceeded if count exceeds the limit of the options.
+func checkDeleteLimit(count int, options DeleteOptions) error {
+	limit := options.MaxAssets
+	if limit == 0 {
+		limit = DefaultDeleteLimit
+	}
+	if limit > 0 && count > limit {
+		return fmt.Errorf("%w: %d assets to delete, at most %d allowed", ErrDeleteLimitExceeded, count, limit)
+	}
+	return nil
+}
+
+// sortChildrenFirst orders the assets by descending depth in the asset trees, so children are deleted
+// before their parents.
+func sortChildrenFirst(assets []api.Asset) []api.Asset {
+	sort.SliceStable(assets, func(i, j int) bool {
+		return len(assets[i].LocationalAssetIdPath)+len(assets[i].FunctionalAssetIdPath) >
+			len(assets[j].LocationalAssetIdPath)+len(assets[j].FunctionalAssetIdPath)
+	})
+	return assets
+}
+
+// listAssets returns all assets accessible with the API key, optionally filtered by asset type and
+// project.
+func listAssets(ctx context.Context, apiEndpoint string, apiKey string, assetType string, projectID string) ([]api.Asset, error) {
+	request := client.NewClientContext(ctx, apiEndpoint).AssetsAPI.
+		GetAssets(client.AuthenticationContextWrap(ctx, apiKey))
+	if assetType != "" {
+		request = request.AssetTypeName(assetType)
+	}
+	if projectID != "" {
+		request = request.ProjectId(projectID)
+	}
+	assets, res, err := request.Execute()
+	err = client.NewAPIError("GetAssets", res, err)
+	if err != nil {
+		err = fmt.Errorf("listing assets: %w", err)
+		tools.LogError(err)
+		return nil, err
+	}
+	return assets, nil
+}
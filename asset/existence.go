@@ -0,0 +1,220 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-eliona/v2/client"
+)
+
+// ExistenceCheckConcurrency is the maximum number of parallel requests UpsertDataBulkIfAssetExists
+// makes to check which assets exist.
+var ExistenceCheckConcurrency = 8
+
+// DefaultExistenceCache is used by the IfAssetExists helpers. It remembers existing assets for one
+// minute, but not missing ones, so that data for assets created by other processes is not dropped.
+// Set it to nil to check the assets on every call.
+var DefaultExistenceCache = NewAssetExistenceCache(time.Minute, 0)
+
+type existenceKey struct {
+	apiEndpoint string
+	assetId     int32
+}
+
+type existenceEntry struct {
+	exists  bool
+	expires time.Time
+}
+
+// AssetExistenceCache remembers whether assets exist, per API endpoint and asset id. Existing assets are
+// remembered for TTL, missing assets for NegativeTTL. A TTL of 0 disables caching of the respective
+// result. Assets upserted or deleted with the helpers of this package update DefaultExistenceCache. A cache
+// created as a struct literal, e.g. &AssetExistenceCache{TTL: time.Minute}, is ready to use.
+type AssetExistenceCache struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+
+	mutex   sync.Mutex
+	entries map[existenceKey]existenceEntry
+}
+
+// NewAssetExistenceCache creates an empty cache.
+func NewAssetExistenceCache(ttl time.Duration, negativeTTL time.Duration) *AssetExistenceCache {
+	return &AssetExistenceCache{TTL: ttl, NegativeTTL: negativeTTL, entries: map[existenceKey]existenceEntry{}}
+}
+
+// Exists returns whether the asset exists. If the result is not cached, the asset is checked
+// with CheckAssetExistenceContext and the result is cached.
+func (c *AssetExistenceCache) Exists(ctx context.Context, apiEndpoint string, apiKey string, assetId int32) (bool, error) {
+	apiEndpoint = cacheEndpoint(ctx, apiEndpoint)
+	if exists, ok := c.get(apiEndpoint, assetId, time.Now()); ok {
+		return exists, nil
+	}
+	existence, err := CheckAssetExistenceContext(ctx, apiEndpoint, apiKey, assetId)
+	if err != nil {
+		return false, err
+	}
+	c.Set(apiEndpoint, assetId, existence == Exists)
+	return existence == Exists, nil
+}
+
+// Set caches whether the asset exists, e.g. after creating it.
+func (c *AssetExistenceCache) Set(apiEndpoint string, assetId int32, exists bool) {
+	if c == nil {
+		return
+	}
+	ttl := c.TTL
+	if !exists {
+		ttl = c.NegativeTTL
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if ttl <= 0 {
+		delete(c.entries, existenceKey{apiEndpoint, assetId})
+		return
+	}
+	if c.entries == nil {
+		c.entries = map[existenceKey]existenceEntry{}
+	}
+	c.entries[existenceKey{apiEndpoint, assetId}] = existenceEntry{exists: exists, expires: time.Now().Add(ttl)}
+}
+
+// Invalidate removes the asset from the cache, so the next check asks Eliona again.
+func (c *AssetExistenceCache) Invalidate(apiEndpoint string, assetId int32) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, existenceKey{apiEndpoint, assetId})
+}
+
+// InvalidateAll empties the cache.
+func (c *AssetExistenceCache) InvalidateAll() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = map[existenceKey]existenceEntry{}
+}
+
+// WarmUp lists all assets accessible with the API key and caches them as existing. Assets missing from
+// the listing are not cached, because they might exist in projects not visible to the key.
+func (c *AssetExistenceCache) WarmUp(ctx context.Context, apiEndpoint string, apiKey string) error {
+	assets, err := listAssets(ctx, apiEndpoint, apiKey, "", "")
+	if err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		if asset.Id.IsSet() && asset.Id.Get() != nil {
+			c.Set(cacheEndpoint(ctx, apiEndpoint), *asset.Id.Get(), true)
+		}
+	}
+	return nil
+}
+
+// cacheEndpoint returns the endpoint of the client passed with client.WithClient if the endpoint is
+// empty, so that the cache entries of different endpoints are kept apart.
+func cacheEndpoint(ctx context.Context, apiEndpoint string) string {
+	if c, ok := client.FromContext(ctx); ok && apiEndpoint == "" {
+		return c.Endpoint()
+	}
+	return apiEndpoint
+}
+
+func (c *AssetExistenceCache) get(apiEndpoint string, assetId int32, now time.Time) (exists bool, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[existenceKey{apiEndpoint, assetId}]
+	if !ok || now.After(entry.expires) {
+		return false, false
+	}
+	return entry.exists, true
+}
+
+// existMany returns which of the given assets exist. Each asset is checked only once, cached results
+// are reused and the remaining assets are checked with at most ExistenceCheckConcurrency parallel
+// requests. The first failing check cancels the others and is returned. If the context is done before
+// all assets are checked, its error is returned. The cache may be nil.
+func (c *AssetExistenceCache) existMany(ctx context.Context, apiEndpoint string, apiKey string, assetIds []int32) (map[int32]bool, error) {
+	result := make(map[int32]bool, len(assetIds))
+	var unchecked []int32
+	now := time.Now()
+	for _, assetId := range assetIds {
+		if _, seen := result[assetId]; seen {
+			continue
+		}
+		exists, ok := c.get(cacheEndpoint(ctx, apiEndpoint), assetId, now)
+		result[assetId] = exists
+		if !ok {
+			unchecked = append(unchecked, assetId)
+		}
+	}
+	if len(unchecked) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	concurrency := max(ExistenceCheckConcurrency, 1)
+	semaphore := make(chan struct{}, concurrency)
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+	for _, assetId := range unchecked {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if err := ctx.Err(); err != nil {
+				// The check is skipped, so the result is incomplete.
+				mutex.Lock()
+				defer mutex.Unlock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			existence, err := CheckAssetExistenceContext(ctx, apiEndpoint, apiKey, assetId)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			result[assetId] = existence == Exists
+			c.Set(cacheEndpoint(ctx, apiEndpoint), assetId, existence == Exists)
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+)
+
+// GenerateAssetType returns the given asset type with one attribute for each field of T having an
+// `eliona` and a `subtype` tag. Embedded and nested structs are flattened like in WalkTaggedFields.
+// Name, subtype, type, unit, precision, min, max, translation and pipeline settings are taken from the
+// tags (see ElionaTag); bool fields become digital attributes. Filterable has no equivalent in asset
+// types and is ignored.
+func GenerateAssetType[T any](assetType api.AssetType) (api.AssetType, error) {
+	fields, err := taggedFields(reflect.TypeFor[T]())
+	if err != nil {
+		return api.AssetType{}, fmt.Errorf("generating asset type %s: %w", assetType.Name, err)
+	}
+
+	names := make(map[string]bool)
+	for _, field := range fields {
+		tag := field.Tag
+		if tag.Subtype == "" {
+			continue
+		}
+		if names[tag.AttributeName] {
+			return api.AssetType{}, fmt.Errorf("generating asset type %s: attribute %s defined twice", assetType.Name, tag.AttributeName)
+		}
+		names[tag.AttributeName] = true
+		assetType.Attributes = append(assetType.Attributes, attributeFromTag(tag, field.Field.Type))
+	}
+	return assetType, nil
+}
+
+func attributeFromTag(tag ElionaTag, fieldType reflect.Type) api.AssetTypeAttribute {
+	attribute := api.AssetTypeAttribute{
+		Name:    tag.AttributeName,
+		Subtype: tag.Subtype,
+		Enable:  common.Ptr(true),
+	}
+	if tag.Type != "" {
+		attribute.Type = *api.NewNullableString(common.Ptr(tag.Type))
+	}
+	if tag.Unit != "" {
+		attribute.Unit = *api.NewNullableString(common.Ptr(tag.Unit))
+	}
+	if tag.Precision != nil {
+		attribute.Precision = *api.NewNullableInt64(tag.Precision)
+	}
+	if tag.Min != nil {
+		attribute.Min = *api.NewNullableFloat64(tag.Min)
+	}
+	if tag.Max != nil {
+		attribute.Max = *api.NewNullableFloat64(tag.Max)
+	}
+	if tag.Translation != nil {
+		attribute.Translation = *api.NewNullableTranslation(tag.Translation)
+	}
+	if tag.AggregationMode != "" {
+		attribute.AggregationMode = *api.NewNullableString(common.Ptr(tag.AggregationMode))
+	}
+	attribute.AggregationRasters = tag.AggregationRasters
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() == reflect.Bool {
+		attribute.IsDigital = *api.NewNullableBool(common.Ptr(true))
+	}
+	return attribute
+}
+
+// WriteAssetTypeFile writes the asset type as JSON file, which InitAssetTypeFile can read.
+func WriteAssetTypeFile(path string, assetType api.AssetType) error {
+	content, err := json.MarshalIndent(assetType, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshalling asset type %s: %w", assetType.Name, err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing asset type file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+)
+
+// ErrAmbiguousGAI is returned by GetAssetByGAI if several assets match the GAI, e.g. in different sites,
+// and by Sync if several existing assets have the same site and GAI.
+var ErrAmbiguousGAI = errors.New("ambiguous GAI")
+
+// AssetFilter selects assets for ListAssets and IterateAssets. Empty fields do not filter. AssetType and
+// ProjectID are filtered by Eliona, the other fields by this package.
+type AssetFilter struct {
+	AssetType string
+	ProjectID string
+	// Tag selects assets having this tag.
+	Tag string
+	// ParentID selects the direct children of this asset in the hierarchy. The default hierarchy is
+	// Locational.
+	ParentID  int32
+	Hierarchy Hierarchy
+}
+
+func (f AssetFilter) matches(asset api.Asset) bool {
+	if f.Tag != "" && !slices.Contains(asset.Tags, f.Tag) {
+		return false
+	}
+	if f.ParentID != 0 {
+		parent := asset.ParentLocationalAssetId
+		if f.Hierarchy == Functional {
+			parent = asset.ParentFunctionalAssetId
+		}
+		if parent.Get() == nil || *parent.Get() != f.ParentID {
+			return false
+		}
+	}
+	return true
+}
+
+// ListAssets returns the assets matching the filter.
+func ListAssets(apiEndpoint string, apiKey string, filter AssetFilter) ([]api.Asset, error) {
+	return ListAssetsContext(context.Background(), apiEndpoint, apiKey, filter)
+}
+
+// ListAssetsContext works like ListAssets using the given context.
+func ListAssetsContext(ctx context.Context, apiEndpoint string, apiKey string, filter AssetFilter) ([]api.Asset, error) {
+	var assets []api.Asset
+	for asset, err := range IterateAssets(ctx, apiEndpoint, apiKey, filter) {
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
+
+// IterateAssets returns an iterator over the assets matching the filter. The assets are requested when
+// the iteration starts. As api-v2 does not page the asset listing, all assets are received and held in
+// memory at once; the iterator only filters them while iterating. If the request fails, the iterator
+// yields the error once.
+func IterateAssets(ctx context.Context, apiEndpoint string, apiKey string, filter AssetFilter) iter.Seq2[api.Asset, error] {
+	return func(yield func(api.Asset, error) bool) {
+		assets, err := listAssets(ctx, apiEndpoint, apiKey, filter.AssetType, filter.ProjectID)
+		if err != nil {
+			yield(api.Asset{}, err)
+			return
+		}
+		for _, asset := range assets {
+			if filter.matches(asset) && !yield(asset, nil) {
+				return
+			}
+		}
+	}
+}
+
+// GetAssetByGAI returns the asset with the given GAI in the site. Assets listed without site are matched by
+// GAI only, as are all assets if the site ID is empty. It returns ErrNotFound if there is no such asset and
+// ErrAmbiguousGAI if several assets match.
+func GetAssetByGAI(apiEndpoint string, apiKey string, siteID string, gai string) (*api.Asset, error) {
+	return GetAssetByGAIContext(context.Background(), apiEndpoint, apiKey, siteID, gai)
+}
+
+// GetAssetByGAIContext works like GetAssetByGAI using the given context.
+func GetAssetByGAIContext(ctx context.Context, apiEndpoint string, apiKey string, siteID string, gai string) (*api.Asset, error) {
+	var candidates []api.Asset
+	for asset, err := range IterateAssets(ctx, apiEndpoint, apiKey, AssetFilter{}) {
+		if err != nil {
+			return nil, err
+		}
+		if asset.GlobalAssetIdentifier != gai {
+			continue
+		}
+		assetSite := stringValue(asset.SiteId)
+		if siteID != "" && assetSite == siteID {
+			return &asset, nil
+		}
+		if siteID == "" || assetSite == "" {
+			candidates = append(candidates, asset)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("getting asset %s in site %s: %w", gai, siteID, ErrNotFound)
+	case 1:
+		return &candidates[0], nil
+	default:
+		return nil, fmt.Errorf("getting asset %s in site %s: %w: %d assets match", gai, siteID, ErrAmbiguousGAI, len(candidates))
+	}
+}
@@ -0,0 +1,314 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+)
+
+// OrphanPolicy defines what Sync does with assets which exist in Eliona, but not in the desired assets.
+type OrphanPolicy string
+
+const (
+	// KeepOrphans leaves orphans untouched and only reports them.
+	KeepOrphans OrphanPolicy = "keep"
+	// MarkOrphansInactive adds the inactive tag to orphans. Orphans which appear again are reactivated.
+	MarkOrphansInactive OrphanPolicy = "inactive"
+	// DeleteOrphans deletes orphans, children before their parents.
+	DeleteOrphans OrphanPolicy = "delete"
+)
+
+// DefaultInactiveTag is the tag MarkOrphansInactive uses if SyncOptions defines no other tag.
+const DefaultInactiveTag = "inactive"
+
+// ErrNoDesiredAssets is returned if Sync should delete orphans, but no assets are desired. An empty list
+// usually means that the third-party system failed, so Sync refuses to delete all assets unless
+// SyncOptions.AllowEmpty is set.
+var ErrNoDesiredAssets = errors.New("no desired assets")
+
+// SyncOptions defines which existing assets Sync compares with the desired assets and how it handles
+// orphans. At least AssetType or GAIPrefix must be set, so assets of other apps are never orphaned.
+type SyncOptions struct {
+	AssetType    string
+	GAIPrefix    string
+	ProjectID    string
+	OrphanPolicy OrphanPolicy
+	InactiveTag  string
+	// DryRun computes the report without changing anything.
+	DryRun bool
+	// MaxDeletes is the maximum number of orphans DeleteOrphans deletes, like DeleteOptions.MaxAssets.
+	// If there are more orphans, Sync returns ErrDeleteLimitExceeded without changing anything.
+	MaxDeletes int
+	// AllowEmpty lets DeleteOrphans delete all existing assets if no assets are desired.
+	AllowEmpty bool
+	// Chunk configures the bulk upsert of created and updated assets.
+	Chunk ChunkOptions
+}
+
+// SyncReport lists the GAIs of the assets Sync created, updated, left unchanged and found orphaned.
+// Failed lists the assets which could not be created or updated. Deactivated and Deleted list the orphans
+// handled according to the orphan policy. In a dry run, Created and Updated list the planned changes.
+type SyncReport struct {
+	Created     []string
+	Updated     []string
+	Unchanged   []string
+	Failed      []string
+	Orphans     []string
+	Deactivated []string
+	Deleted     []string
+}
+
+func (r SyncReport) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d unchanged, %d failed, %d orphans (%d deactivated, %d deleted)",
+		len(r.Created), len(r.Updated), len(r.Unchanged), len(r.Failed), len(r.Orphans), len(r.Deactivated), len(r.Deleted))
+}
+
+// Sync reconciles the desired assets with the assets existing in Eliona. Existing assets are selected by
+// asset type, project and GAI prefix and matched by site and GAI. If several existing assets have the
+// same site and GAI, e.g. in different projects, Sync returns ErrAmbiguousGAI without changing anything.
+// New assets are created, changed assets
+// updated and existing assets missing from the desired ones are handled according to the orphan policy.
+// The IDs of all desired assets are set with SetAssetID.
+func Sync(apiEndpoint string, apiKey string, desired []AssetLikeWithParentReferences, options SyncOptions) (SyncReport, error) {
+	return SyncContext(context.Background(), apiEndpoint, apiKey, desired, options)
+}
+
+// SyncContext works like Sync using the given context.
+func SyncContext(ctx context.Context, apiEndpoint string, apiKey string, desired []AssetLikeWithParentReferences, options SyncOptions) (SyncReport, error) {
+	var report SyncReport
+	if options.AssetType == "" && options.GAIPrefix == "" {
+		return report, errors.New("syncing assets: asset type or GAI prefix must be set")
+	}
+	if options.OrphanPolicy == "" {
+		options.OrphanPolicy = KeepOrphans
+	}
+	if options.InactiveTag == "" {
+		options.InactiveTag = DefaultInactiveTag
+	}
+	if options.OrphanPolicy == DeleteOrphans && len(desired) == 0 && !options.AllowEmpty {
+		return report, fmt.Errorf("syncing assets: %w", ErrNoDesiredAssets)
+	}
+
+	existing, err := listSyncScope(ctx, apiEndpoint, apiKey, options)
+	if err != nil {
+		return report, err
+	}
+	sorted, err := sortAssetLikesByDependencies(desired)
+	if err != nil {
+		return report, fmt.Errorf("sorting assets: %w", err)
+	}
+
+	matched := make(map[int]bool)
+	var upserts []api.Asset
+	var upserted []AssetLikeWithParentReferences
+	var isNew []bool
+	for _, assetLike := range sorted {
+		index, found := existing.find(assetLike.GetSiteID(), assetLike.GetGAI())
+		apiAsset := assetLikeToApiAsset(assetLike)
+		if !found {
+			upserts = append(upserts, apiAsset)
+			upserted = append(upserted, assetLike)
+			isNew = append(isNew, true)
+			continue
+		}
+		matched[index] = true
+		current := existing.assets[index]
+		apiAsset.Tags = slices.DeleteFunc(slices.Clone(current.Tags), func(tag string) bool { return tag == options.InactiveTag })
+		if !assetChanged(current, apiAsset) {
+			report.Unchanged = append(report.Unchanged, assetLike.GetGAI())
+			if !options.DryRun {
+				if err := assetLike.SetAssetID(current.GetId()); err != nil {
+					return report, fmt.Errorf("setting asset ID: %w", err)
+				}
+			}
+			continue
+		}
+		upserts = append(upserts, apiAsset)
+		upserted = append(upserted, assetLike)
+		isNew = append(isNew, false)
+	}
+
+	var orphans []api.Asset
+	for index, asset := range existing.assets {
+		if !matched[index] {
+			orphans = append(orphans, asset)
+			report.Orphans = append(report.Orphans, asset.GlobalAssetIdentifier)
+		}
+	}
+	deleteOptions := DeleteOptions{MaxAssets: options.MaxDeletes}
+	if options.OrphanPolicy == DeleteOrphans {
+		if err := checkDeleteLimit(len(orphans), deleteOptions); err != nil {
+			return report, fmt.Errorf("deleting orphans: %w", err)
+		}
+	}
+	if options.DryRun {
+		for i, assetLike := range upserted {
+			report.addUpsert(assetLike.GetGAI(), isNew[i])
+		}
+		return report, nil
+	}
+
+	if len(upserts) > 0 {
+		result := UpsertAssetsBulkGAIChunkedContext(ctx, apiEndpoint, apiKey, upserts, options.Chunk)
+		failed := make(map[string]bool, len(result.Failed))
+		for _, item := range result.Failed {
+			failed[syncKey(stringValue(item.Item.SiteId), item.Item.GlobalAssetIdentifier)] = true
+		}
+		var succeeded []AssetLikeWithParentReferences
+		for i, assetLike := range upserted {
+			if failed[syncKey(assetLike.GetSiteID(), assetLike.GetGAI())] {
+				report.Failed = append(report.Failed, assetLike.GetGAI())
+				continue
+			}
+			report.addUpsert(assetLike.GetGAI(), isNew[i])
+			succeeded = append(succeeded, assetLike)
+		}
+		if err := setAssetIDs(succeeded, result.Succeeded); err != nil {
+			return report, err
+		}
+		if err := result.Err(); err != nil {
+			return report, fmt.Errorf("upserting assets: %w", err)
+		}
+	}
+
+	switch options.OrphanPolicy {
+	case KeepOrphans:
+	case MarkOrphansInactive:
+		for _, orphan := range orphans {
+			if slices.Contains(orphan.Tags, options.InactiveTag) {
+				continue
+			}
+			orphan.Tags = append(slices.Clone(orphan.Tags), options.InactiveTag)
+			orphan.ChildrenInfo = nil
+			if _, err := UpsertAssetContext(ctx, apiEndpoint, apiKey, orphan); err != nil {
+				return report, fmt.Errorf("marking asset %s inactive: %w", orphan.GlobalAssetIdentifier, err)
+			}
+			report.Deactivated = append(report.Deactivated, orphan.GlobalAssetIdentifier)
+		}
+	case DeleteOrphans:
+		orphans = sortChildrenFirst(orphans)
+		deleted, err := deleteAssets(ctx, apiEndpoint, apiKey, orphans, deleteOptions)
+		for _, orphan := range deleted {
+			report.Deleted = append(report.Deleted, orphan.GlobalAssetIdentifier)
+		}
+		if err != nil {
+			return report, fmt.Errorf("deleting orphans: %w", err)
+		}
+	default:
+		return report, fmt.Errorf("unknown orphan policy %q", options.OrphanPolicy)
+	}
+	return report, nil
+}
+
+func (r *SyncReport) addUpsert(gai string, created bool) {
+	if created {
+		r.Created = append(r.Created, gai)
+	} else {
+		r.Updated = append(r.Updated, gai)
+	}
+}
+
+// syncScope holds the existing assets Sync compares with the desired ones.
+type syncScope struct {
+	assets []api.Asset
+	index  map[string]int
+}
+
+func syncKey(siteID string, gai string) string {
+	return siteID + "\x00" + gai
+}
+
+// find returns the index of the existing asset. Assets listed without site are matched by GAI only.
+func (s syncScope) find(siteID string, gai string) (int, bool) {
+	if index, ok := s.index[syncKey(siteID, gai)]; ok {
+		return index, true
+	}
+	index, ok := s.index[syncKey("", gai)]
+	return index, ok
+}
+
+func listSyncScope(ctx context.Context, apiEndpoint string, apiKey string, options SyncOptions) (syncScope, error) {
+	assets, err := listAssets(ctx, apiEndpoint, apiKey, options.AssetType, options.ProjectID)
+	if err != nil {
+		return syncScope{}, err
+	}
+	scope := syncScope{index: map[string]int{}}
+	for _, asset := range assets {
+		if !strings.HasPrefix(asset.GlobalAssetIdentifier, options.GAIPrefix) {
+			continue
+		}
+		key := syncKey(stringValue(asset.SiteId), asset.GlobalAssetIdentifier)
+		if other, ok := scope.index[key]; ok {
+			// Only one of the assets could be matched and the other one would become an orphan.
+			return syncScope{}, fmt.Errorf("syncing assets: %w: assets %d and %d have GAI %s in site %q, set the project ID",
+				ErrAmbiguousGAI, scope.assets[other].GetId(), asset.GetId(), asset.GlobalAssetIdentifier, stringValue(asset.SiteId))
+		}
+		scope.index[key] = len(scope.assets)
+		scope.assets = append(scope.assets, asset)
+	}
+	return scope, nil
+}
+
+// assetChanged compares the fields Sync manages. Parents are only compared if Eliona reports them.
+func assetChanged(current api.Asset, desired api.Asset) bool {
+	if current.AssetType != desired.AssetType ||
+		current.GetName() != desired.GetName() ||
+		current.GetDescription() != desired.GetDescription() ||
+		!slices.Equal(current.Tags, desired.Tags) {
+		return true
+	}
+	if current.ParentLocationalIdentifier.IsSet() && stringValue(current.ParentLocationalIdentifier) != stringValue(desired.ParentLocationalIdentifier) {
+		return true
+	}
+	if current.ParentFunctionalIdentifier.IsSet() && stringValue(current.ParentFunctionalIdentifier) != stringValue(desired.ParentFunctionalIdentifier) {
+		return true
+	}
+	return false
+}
+
+func stringValue(s api.NullableString) string {
+	if s.Get() == nil {
+		return ""
+	}
+	return *s.Get()
+}
+
+// setAssetIDs sets the IDs of the upserted assets, matched by site and GAI like syncScope.find does.
+func setAssetIDs(assetLikes []AssetLikeWithParentReferences, upserted []api.Asset) error {
+	ids := make(map[string]int32, len(upserted))
+	for _, asset := range upserted {
+		ids[syncKey(stringValue(asset.SiteId), asset.GlobalAssetIdentifier)] = asset.GetId()
+	}
+	for _, assetLike := range assetLikes {
+		id, ok := ids[syncKey(assetLike.GetSiteID(), assetLike.GetGAI())]
+		if !ok {
+			id, ok = ids[syncKey("", assetLike.GetGAI())]
+		}
+		if !ok || id == 0 {
+			return fmt.Errorf("GAI '%v' has no asset ID in response from APIv2", assetLike.GetGAI())
+		}
+		if err := assetLike.SetAssetID(id); err != nil {
+			return fmt.Errorf("setting asset ID: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,42 +1,321 @@
 package asset
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 
 	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
 )
 
+// ErrInvalidTag is wrapped by the errors ParseTag returns.
+var ErrInvalidTag = errors.New("invalid eliona tag")
+
+// ElionaTag describes a struct field tagged like
+//
+//	Temperature float64 `eliona:"temperature,filterable,unit=°C,precision=1,min=-20,max=50,type=temperature" subtype:"input" translation:"en=Temperature;de=Temperatur" pipeline:"mode=avg;rasters=M15,H1"`
+//
+// The eliona tag starts with the attribute name followed by these options:
+//
+//	filterable      the field is used by asset filters, see utils.StructToMap
+//	omitempty       zero values are not written
+//	readonly        the field is read from Eliona, but never written
+//	subtype=<s>     the data subtype, alternatively to the subtype tag
+//	prefix=<p>      the fields of the nested struct are attributes prefixed with p, see WalkTaggedFields
+//	unit=<u>, type=<t>, precision=<n>, min=<x>, max=<x>
+//	                settings of the generated asset type attribute
+//
+// Fields tagged with eliona:"-" are ignored. The translation and pipeline tags are used to generate
+// asset type attributes.
 type ElionaTag struct {
 	AttributeName string
 	Filterable    bool
+	OmitEmpty     bool
+	ReadOnly      bool
 	Subtype       api.DataSubtype
+	Prefix        string
+
+	Unit               string
+	Type               string
+	Precision          *int64
+	Min                *float64
+	Max                *float64
+	Translation        *api.Translation
+	AggregationMode    string
+	AggregationRasters []string
 }
 
+// ParseElionaTag works like ParseTag, but ignores invalid options.
+//
+// Deprecated: Use ParseTag, which reports invalid tags.
 func ParseElionaTag(fieldType reflect.StructField) (result ElionaTag, ok bool) {
+	result, ok, _ = ParseTag(fieldType)
+	return result, ok
+}
+
+// ParseTag parses the tags of the field, see ElionaTag. It returns false for fields without eliona tag
+// or tagged with eliona:"-". For invalid tags, it returns an error wrapping ErrInvalidTag listing all
+// problems; the result contains the valid options anyway.
+func ParseTag(fieldType reflect.StructField) (result ElionaTag, ok bool, err error) {
 	tag := fieldType.Tag
 
 	elionaTag, ok := tag.Lookup("eliona")
-	if !ok {
-		return ElionaTag{}, false
+	if !ok || elionaTag == "-" {
+		return ElionaTag{}, false, nil
 	}
 
+	var problems []string
 	elionaValues := strings.Split(elionaTag, ",")
+	result.AttributeName = strings.TrimSpace(elionaValues[0])
 
-	attributeName := elionaValues[0]
-	filterable := false
+	subtypeTag, hasSubtypeTag := tag.Lookup("subtype")
+	result.Subtype = api.DataSubtype(subtypeTag)
 
 	for _, value := range elionaValues[1:] {
-		if value == "filterable" {
-			filterable = true
-			break
+		key, option, hasOption := strings.Cut(strings.TrimSpace(value), "=")
+		switch key {
+		case "filterable", "omitempty", "readonly":
+			if hasOption {
+				problems = append(problems, fmt.Sprintf("option %s takes no value", key))
+				continue
+			}
+			switch key {
+			case "filterable":
+				result.Filterable = true
+			case "omitempty":
+				result.OmitEmpty = true
+			case "readonly":
+				result.ReadOnly = true
+			}
+			continue
+		case "subtype", "prefix", "unit", "type", "precision", "min", "max":
+			if !hasOption || option == "" {
+				problems = append(problems, fmt.Sprintf("option %s needs a value", key))
+				continue
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("unknown option %q", value))
+			continue
+		}
+		switch key {
+		case "subtype":
+			if hasSubtypeTag && subtypeTag != option {
+				problems = append(problems, fmt.Sprintf("subtype %s conflicts with subtype tag %s", option, subtypeTag))
+				continue
+			}
+			result.Subtype = api.DataSubtype(option)
+		case "prefix":
+			result.Prefix = option
+		case "unit":
+			result.Unit = option
+		case "type":
+			result.Type = option
+		case "precision":
+			precision, err := strconv.ParseInt(option, 10, 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("precision %q", option))
+				continue
+			}
+			result.Precision = &precision
+		case "min", "max":
+			limit, err := strconv.ParseFloat(option, 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s %q", key, option))
+				continue
+			}
+			if key == "min" {
+				result.Min = &limit
+			} else {
+				result.Max = &limit
+			}
+		}
+	}
+	if result.AttributeName == "" && result.Prefix == "" {
+		problems = append([]string{"missing attribute name"}, problems...)
+	}
+	if result.Subtype != "" && !result.Subtype.IsValid() {
+		problems = append(problems, fmt.Sprintf("invalid subtype %q", result.Subtype))
+		result.Subtype = ""
+	}
+
+	if translationTag, ok := tag.Lookup("translation"); ok {
+		translation := api.Translation{}
+		for _, entry := range strings.Split(translationTag, ";") {
+			language, text, _ := strings.Cut(entry, "=")
+			switch strings.TrimSpace(language) {
+			case "de":
+				translation.De = &text
+			case "en":
+				translation.En = &text
+			case "fr":
+				translation.Fr = &text
+			case "it":
+				translation.It = &text
+			default:
+				problems = append(problems, fmt.Sprintf("translation %q", entry))
+			}
+		}
+		result.Translation = &translation
+	}
+
+	if pipelineTag, ok := tag.Lookup("pipeline"); ok {
+		for _, entry := range strings.Split(pipelineTag, ";") {
+			key, option, _ := strings.Cut(entry, "=")
+			switch strings.TrimSpace(key) {
+			case "mode":
+				result.AggregationMode = option
+			case "rasters":
+				result.AggregationRasters = strings.Split(option, ",")
+			default:
+				problems = append(problems, fmt.Sprintf("pipeline %q", entry))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return result, true, fmt.Errorf("%w on field %s: %s", ErrInvalidTag, fieldType.Name, strings.Join(problems, ", "))
+	}
+	return result, true, nil
+}
+
+// WalkTaggedFields calls fn for each field of the struct having an eliona tag, in the order of the
+// fields. The data may be a struct or a pointer to a struct.
+//
+//   - Embedded structs without eliona tag are flattened, i.e. their fields are walked as if they were
+//     fields of the outer struct.
+//   - Nested structs tagged with the prefix option are flattened, too. The prefix is prepended to the
+//     attribute names of their fields, and fields without subtype inherit the subtype of the nested
+//     struct. Nested structs tagged without prefix are passed to fn as one value, which is written as
+//     JSON object.
+//   - Pointers are dereferenced. Nil pointers are treated as absent fields and skipped.
+//
+// The attribute name of the tag passed to fn includes all prefixes. Fields with invalid tags are skipped
+// and reported in the returned error.
+func WalkTaggedFields(data any, fn func(tag ElionaTag, value reflect.Value)) error {
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("walking tagged fields: %T is not a struct", data)
+	}
+	fields, err := taggedFields(value.Type())
+	for _, field := range fields {
+		fieldValue, ok := fieldByIndex(value, field.Index, false)
+		for ok && fieldValue.Kind() == reflect.Pointer {
+			// Nil pointers are absent.
+			ok = !fieldValue.IsNil()
+			fieldValue = fieldValue.Elem()
 		}
+		if !ok || !fieldValue.CanInterface() {
+			// Fields promoted from unexported embedded structs cannot be read.
+			continue
+		}
+		fn(field.Tag, fieldValue)
 	}
-	subtype := tag.Get("subtype")
+	return err
+}
 
-	return ElionaTag{
-		AttributeName: attributeName,
-		Filterable:    filterable,
-		Subtype:       api.DataSubtype(subtype),
-	}, true
+// taggedField is a field with eliona tag found in a struct type or its embedded and nested structs. The
+// attribute name of the tag includes all prefixes and the subtype is inherited from nested structs.
+// Name is the path of the field, e.g. "Battery.Level", Index the path of field indices.
+type taggedField struct {
+	Tag   ElionaTag
+	Field reflect.StructField
+	Name  string
+	Index []int
+}
+
+// taggedFields returns the tagged fields of the struct type as WalkTaggedFields walks them. Fields with
+// invalid tags are left out and reported in the returned error.
+func taggedFields(structType reflect.Type) ([]taggedField, error) {
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", structType)
+	}
+	collector := fieldCollector{visiting: map[reflect.Type]bool{structType: true}}
+	collector.collect(structType, nil, "", "", "")
+	return collector.fields, errors.Join(collector.errs...)
+}
+
+type fieldCollector struct {
+	fields   []taggedField
+	errs     []error
+	visiting map[reflect.Type]bool
+}
+
+func (c *fieldCollector) collect(structType reflect.Type, index []int, path string, prefix string, subtype api.DataSubtype) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			// Skip unexported fields.
+			continue
+		}
+		tag, ok, err := ParseTag(field)
+		if err != nil {
+			c.errs = append(c.errs, err)
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		nested := fieldType.Kind() == reflect.Struct && fieldType != timeType
+		fieldIndex := append(slices.Clone(index), i)
+		fieldPath := path + field.Name
+
+		if !ok {
+			if field.Anonymous && nested {
+				c.nest(field, fieldType, fieldIndex, fieldPath, prefix, subtype)
+			}
+			continue
+		}
+		if tag.Subtype == "" {
+			tag.Subtype = subtype
+		}
+		if tag.Prefix != "" {
+			if !nested {
+				c.errs = append(c.errs, fmt.Errorf("%w on field %s: prefix on field which is no struct", ErrInvalidTag, field.Name))
+				continue
+			}
+			c.nest(field, fieldType, fieldIndex, fieldPath, prefix+tag.Prefix, tag.Subtype)
+			continue
+		}
+		tag.AttributeName = prefix + tag.AttributeName
+		c.fields = append(c.fields, taggedField{Tag: tag, Field: field, Name: fieldPath, Index: fieldIndex})
+	}
+}
+
+func (c *fieldCollector) nest(field reflect.StructField, fieldType reflect.Type, index []int, path string, prefix string, subtype api.DataSubtype) {
+	if c.visiting[fieldType] {
+		c.errs = append(c.errs, fmt.Errorf("%w on field %s: %s contains itself", ErrInvalidTag, field.Name, fieldType))
+		return
+	}
+	c.visiting[fieldType] = true
+	defer delete(c.visiting, fieldType)
+	c.collect(fieldType, index, path+".", prefix, subtype)
+}
+
+// fieldByIndex returns the nested field of the struct value. Nil pointers on the way are allocated if
+// allocate is set and the pointer can be set; otherwise, the field is reported as absent.
+func fieldByIndex(value reflect.Value, index []int, allocate bool) (reflect.Value, bool) {
+	for _, i := range index {
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				if !allocate || !value.CanSet() {
+					return reflect.Value{}, false
+				}
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(i)
+	}
+	return value, true
 }
@@ -0,0 +1,156 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoApiKey is returned by providers which have no API key for the requested tenant.
+var ErrNoApiKey = errors.New("no api key")
+
+// ApiKeyProvider returns the API key an app uses to access Eliona on behalf of a tenant.
+type ApiKeyProvider interface {
+	ApiKey(ctx context.Context, tenantId uuid.UUID) (string, error)
+}
+
+// ApiKeyProviderFunc adapts a function to the ApiKeyProvider interface.
+type ApiKeyProviderFunc func(ctx context.Context, tenantId uuid.UUID) (string, error)
+
+func (f ApiKeyProviderFunc) ApiKey(ctx context.Context, tenantId uuid.UUID) (string, error) {
+	return f(ctx, tenantId)
+}
+
+// EnvApiKeyProvider reads the key of a tenant from the environment variable <Prefix>_<TENANT_ID>, e.g.
+// API_TOKEN_0B5E1B9A_95C4_4B2B_9F3C_5A3F2C1D7E11. If this variable is not set, the variable <Prefix>
+// itself is used for all tenants. The default prefix is API_TOKEN.
+type EnvApiKeyProvider struct {
+	Prefix string
+}
+
+func (p EnvApiKeyProvider) ApiKey(_ context.Context, tenantId uuid.UUID) (string, error) {
+	prefix := p.Prefix
+	if prefix == "" {
+		prefix = "API_TOKEN"
+	}
+	tenantVariable := prefix + "_" + strings.ToUpper(strings.ReplaceAll(tenantId.String(), "-", "_"))
+	if key := os.Getenv(tenantVariable); key != "" {
+		return key, nil
+	}
+	if key := os.Getenv(prefix); key != "" {
+		return key, nil
+	}
+	return "", fmt.Errorf("%w: neither %s nor %s is set", ErrNoApiKey, tenantVariable, prefix)
+}
+
+// HTTPApiKeyProvider fetches the key of a tenant from an HTTP endpoint. The placeholder {tenantId} in
+// URL is replaced by the tenant ID. The request is authenticated with AuthKey in the X-API-Key header
+// like requests to api-v2, and the endpoint must respond with a JSON object like {"key": "..."}.
+type HTTPApiKeyProvider struct {
+	URL        string
+	AuthKey    string
+	HTTPClient *http.Client
+}
+
+func (p HTTPApiKeyProvider) ApiKey(ctx context.Context, tenantId uuid.UUID) (string, error) {
+	url := strings.ReplaceAll(p.URL, "{tenantId}", tenantId.String())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request for api key: %w", err)
+	}
+	request.Header.Set("X-API-Key", p.AuthKey)
+	request.Header.Set("Accept", "application/json")
+
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("requesting api key for tenant %s: %w", tenantId, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: tenant %s", ErrNoApiKey, tenantId)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting api key for tenant %s: %s", tenantId, response.Status)
+	}
+
+	var body struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding api key for tenant %s: %w", tenantId, err)
+	}
+	if body.Key == "" {
+		return "", fmt.Errorf("%w: tenant %s", ErrNoApiKey, tenantId)
+	}
+	return body.Key, nil
+}
+
+// CachingApiKeyProvider caches the keys returned by another provider for a fixed time.
+type CachingApiKeyProvider struct {
+	provider ApiKeyProvider
+	ttl      time.Duration
+	mutex    sync.Mutex
+	entries  map[uuid.UUID]cachedApiKey
+}
+
+type cachedApiKey struct {
+	key     string
+	expires time.Time
+}
+
+// NewCachingApiKeyProvider returns a provider which asks the given provider at most once per ttl and tenant.
+func NewCachingApiKeyProvider(provider ApiKeyProvider, ttl time.Duration) *CachingApiKeyProvider {
+	return &CachingApiKeyProvider{provider: provider, ttl: ttl, entries: make(map[uuid.UUID]cachedApiKey)}
+}
+
+func (p *CachingApiKeyProvider) ApiKey(ctx context.Context, tenantId uuid.UUID) (string, error) {
+	p.mutex.Lock()
+	entry, ok := p.entries[tenantId]
+	p.mutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.key, nil
+	}
+
+	key, err := p.provider.ApiKey(ctx, tenantId)
+	if err != nil {
+		return "", err
+	}
+	p.mutex.Lock()
+	p.entries[tenantId] = cachedApiKey{key: key, expires: time.Now().Add(p.ttl)}
+	p.mutex.Unlock()
+	return key, nil
+}
+
+// Invalidate removes the cached key of the tenant, e.g. after the API rejected it.
+func (p *CachingApiKeyProvider) Invalidate(tenantId uuid.UUID) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.entries, tenantId)
+}
@@ -17,6 +17,10 @@ package client
 
 import (
 	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
 
 	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
 	"github.com/eliona-smart-building-assistant/go-utils/common"
@@ -30,17 +34,139 @@ func ApiKeyString() string {
 	return common.Getenv("API_TOKEN", "not defined")
 }
 
-func NewClient(apiEndpoint string) *api.APIClient {
+// Config defines how a Client accesses the Eliona API. Only Endpoint is required. Timeout and Transport
+// are applied to a copy of HTTPClient, or to a new http.Client if HTTPClient is nil. If Retry allows more
+// than one attempt, transient failures are retried according to the policy, see RetryPolicy. If
+// RateLimiter is set, every request, including each retry, is subject to its limits.
+type Config struct {
+	Endpoint    string
+	ApiKey      string
+	Timeout     time.Duration
+	HTTPClient  *http.Client
+	Transport   http.RoundTripper
+	UserAgent   string
+	Retry       RetryPolicy
+	RateLimiter *RateLimiter
+}
+
+// Client is a long-lived client for the Eliona API. Create it once with New and register it with
+// Register. Afterward, all functions of this library called with the endpoint of the client use it,
+// so connections are pooled and the configured transport applies to every request. To use several
+// clients for the same endpoint, e.g. one per tenant or in tests, pass the client with WithClient to
+// the context functions of this library instead.
+type Client struct {
+	*api.APIClient
+	endpoint string
+	apiKey   string
+}
+
+// New creates a client with the given configuration.
+func New(config Config) *Client {
+	httpClient := &http.Client{}
+	if config.HTTPClient != nil {
+		copied := *config.HTTPClient
+		httpClient = &copied
+	}
+	if config.Timeout > 0 {
+		httpClient.Timeout = config.Timeout
+	}
+	if config.Transport != nil {
+		httpClient.Transport = config.Transport
+	}
+	if config.RateLimiter != nil {
+		basePath := ""
+		if endpoint, err := url.Parse(config.Endpoint); err == nil {
+			basePath = endpoint.Path
+		}
+		httpClient.Transport = config.RateLimiter.transport(httpClient.Transport, basePath)
+	}
+	if config.Retry.MaxAttempts > 1 {
+		httpClient.Transport = RetryTransport(httpClient.Transport, config.Retry)
+	}
+
 	cfg := api.NewConfiguration()
-	cfg.Servers = api.ServerConfigurations{{URL: apiEndpoint}}
-	return api.NewAPIClient(cfg)
+	cfg.Servers = api.ServerConfigurations{{URL: config.Endpoint}}
+	cfg.HTTPClient = httpClient
+	if config.UserAgent != "" {
+		cfg.UserAgent = config.UserAgent
+	}
+	return &Client{APIClient: api.NewAPIClient(cfg), endpoint: config.Endpoint, apiKey: config.ApiKey}
+}
+
+// Endpoint returns the endpoint the client was created for.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// ApiKey returns the API key the client was created with.
+func (c *Client) ApiKey() string {
+	return c.apiKey
+}
+
+// AuthenticationContext wraps the context with the API key of the client.
+func (c *Client) AuthenticationContext(ctx context.Context) context.Context {
+	return AuthenticationContextWrap(ctx, c.apiKey)
+}
+
+var (
+	clientsMutex sync.Mutex
+	clients      = make(map[string]*api.APIClient)
+)
+
+// Register makes all functions of this library use the given client for requests to its endpoint.
+// A client registered before for the same endpoint is replaced.
+func Register(c *Client) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	clients[c.endpoint] = c.APIClient
+}
+
+type clientKey struct{}
+
+// WithClient returns a context which makes the functions of this library called with it use the given
+// client instead of the registered one. Functions called with an empty API key use the key of the client.
+func WithClient(ctx context.Context, c *Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// FromContext returns the client set with WithClient.
+func FromContext(ctx context.Context) (*Client, bool) {
+	c, ok := ctx.Value(clientKey{}).(*Client)
+	return c, ok && c != nil
+}
+
+// NewClientContext returns the client set with WithClient if the endpoint is empty or matches the
+// endpoint of this client. Otherwise, it works like NewClient.
+func NewClientContext(ctx context.Context, apiEndpoint string) *api.APIClient {
+	if c, ok := FromContext(ctx); ok && (apiEndpoint == "" || apiEndpoint == c.endpoint) {
+		return c.APIClient
+	}
+	return NewClient(apiEndpoint)
+}
+
+// NewClient returns the client registered for the endpoint. If no client is registered, a client with
+// default configuration is created on first use and reused afterward.
+func NewClient(apiEndpoint string) *api.APIClient {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	if c, ok := clients[apiEndpoint]; ok {
+		return c
+	}
+	c := New(Config{Endpoint: apiEndpoint}).APIClient
+	clients[apiEndpoint] = c
+	return c
 }
 
 func AuthenticationContext(apiKey string) context.Context {
 	return AuthenticationContextWrap(context.Background(), apiKey)
 }
 
+// AuthenticationContextWrap wraps the context with the API key. If the key is empty, the key of the
+// client set with WithClient is used.
 func AuthenticationContextWrap(ctx context.Context, apiKey string) context.Context {
+	if c, ok := FromContext(ctx); ok && apiKey == "" {
+		apiKey = c.apiKey
+	}
 	apiKeys := map[string]api.APIKey{
 		"ApiKeyAuth": {Key: apiKey},
 	}
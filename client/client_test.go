@@ -0,0 +1,284 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTenant = uuid.MustParse("0b5e1b9a-95c4-4b2b-9f3c-5a3f2c1d7e11")
+
+func TestEnvApiKeyProvider(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "")
+	_, err := EnvApiKeyProvider{Prefix: "TEST_TOKEN"}.ApiKey(context.Background(), testTenant)
+	assert.ErrorIs(t, err, ErrNoApiKey)
+
+	t.Setenv("TEST_TOKEN", "default")
+	key, err := EnvApiKeyProvider{Prefix: "TEST_TOKEN"}.ApiKey(context.Background(), testTenant)
+	assert.NoError(t, err)
+	assert.Equal(t, "default", key)
+
+	t.Setenv("TEST_TOKEN_0B5E1B9A_95C4_4B2B_9F3C_5A3F2C1D7E11", "tenant")
+	key, err = EnvApiKeyProvider{Prefix: "TEST_TOKEN"}.ApiKey(context.Background(), testTenant)
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant", key)
+}
+
+func TestHTTPApiKeyProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" || r.URL.Path != "/keys/"+testTenant.String() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"key": "tenant-key"}`))
+	}))
+	defer server.Close()
+
+	provider := HTTPApiKeyProvider{URL: server.URL + "/keys/{tenantId}", AuthKey: "secret"}
+	key, err := provider.ApiKey(context.Background(), testTenant)
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-key", key)
+
+	_, err = provider.ApiKey(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrNoApiKey)
+}
+
+func TestCachingApiKeyProvider(t *testing.T) {
+	calls := 0
+	provider := NewCachingApiKeyProvider(ApiKeyProviderFunc(func(context.Context, uuid.UUID) (string, error) {
+		calls++
+		return "key", nil
+	}), time.Hour)
+
+	for i := 0; i < 3; i++ {
+		key, err := provider.ApiKey(context.Background(), testTenant)
+		assert.NoError(t, err)
+		assert.Equal(t, "key", key)
+	}
+	assert.Equal(t, 1, calls)
+
+	provider.Invalidate(testTenant)
+	_, _ = provider.ApiKey(context.Background(), testTenant)
+	assert.Equal(t, 2, calls)
+}
+
+type recordingTransport struct {
+	userAgents []string
+}
+
+func (t *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.userAgents = append(t.userAgents, request.Header.Get("User-Agent"))
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func TestRegister(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version": "v9.2.0"}`))
+	}))
+	defer server.Close()
+
+	assert.Same(t, NewClient(server.URL), NewClient(server.URL))
+
+	transport := &recordingTransport{}
+	c := New(Config{Endpoint: server.URL, ApiKey: "key", Timeout: time.Second, Transport: transport, UserAgent: "test-app"})
+	Register(c)
+	assert.Same(t, c.APIClient, NewClient(server.URL))
+
+	_, _, err := NewClient(server.URL).VersionAPI.GetVersion(c.AuthenticationContext(context.Background())).Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test-app"}, transport.userAgents)
+}
+
+func TestWithClient(t *testing.T) {
+	var apiKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeys = append(apiKeys, r.Header.Get("X-API-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 1, "projectId": "1", "globalAssetIdentifier": "gai", "assetType": "type"}`))
+	}))
+	defer server.Close()
+
+	tenantA := New(Config{Endpoint: server.URL, ApiKey: "tenant-a"})
+	tenantB := New(Config{Endpoint: server.URL, ApiKey: "tenant-b"})
+	ctxA := WithClient(context.Background(), tenantA)
+	ctxB := WithClient(context.Background(), tenantB)
+	assert.Same(t, tenantA.APIClient, NewClientContext(ctxA, server.URL))
+	assert.Same(t, tenantB.APIClient, NewClientContext(ctxB, ""))
+	assert.NotSame(t, tenantA.APIClient, NewClientContext(ctxA, "http://other"))
+	assert.Same(t, NewClient(server.URL), NewClientContext(context.Background(), server.URL))
+
+	for _, ctx := range []context.Context{ctxA, ctxB} {
+		_, _, err := NewClientContext(ctx, server.URL).AssetsAPI.GetAssetById(AuthenticationContextWrap(ctx, ""), 1).Execute()
+		assert.NoError(t, err)
+	}
+	_, _, err := NewClientContext(ctxA, server.URL).AssetsAPI.GetAssetById(AuthenticationContextWrap(ctxA, "explicit"), 1).Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tenant-a", "tenant-b", "explicit"}, apiKeys)
+}
+
+func TestRetryTransport(t *testing.T) {
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts[r.Method]++
+		body, _ := io.ReadAll(r.Body)
+		if attempts[r.Method] < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{Transport: RetryTransport(nil, RetryPolicy{MaxAttempts: 5, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})}
+
+	request, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("payload"))
+	response, err := httpClient.Do(request)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "payload", string(body))
+	assert.Equal(t, 3, attempts[http.MethodPut])
+
+	// POST is not idempotent and therefore not retried.
+	response, err = httpClient.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, 1, attempts[http.MethodPost])
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{Transport: RetryTransport(nil, RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond})}
+	response, err := httpClient.Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryDelay(t *testing.T) {
+	transport := &retryTransport{policy: DefaultRetryPolicy()}
+	response := &http.Response{Header: http.Header{"Retry-After": []string{"3600"}}}
+	assert.Equal(t, 8*time.Second, transport.delay(1, response))
+	response.Header.Set("Retry-After", "2")
+	assert.Equal(t, 2*time.Second, transport.delay(1, response))
+	for range 100 {
+		assert.LessOrEqual(t, transport.delay(10, nil), 8*time.Second)
+		assert.GreaterOrEqual(t, transport.delay(1, nil), 500*time.Millisecond)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	delay, ok := parseRetryAfter("7")
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, delay)
+
+	_, ok = parseRetryAfter("soon")
+	assert.False(t, ok)
+}
+
+func TestRateLimiterZeroValue(t *testing.T) {
+	limiter := &RateLimiter{}
+	for range 3 {
+		wait, err := limiter.reserve("assets", time.Now())
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+	assert.Equal(t, int64(3), limiter.Stats().Requests)
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{
+		Global:       RateLimit{Rate: 10, Burst: 2},
+		PerOperation: map[string]RateLimit{"data-bulk": {Rate: 1, Burst: 1}},
+	})
+	now := time.Now()
+
+	wait, err := limiter.reserve("assets", now)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+	wait, _ = limiter.reserve("data-bulk", now)
+	assert.Zero(t, wait)
+	wait, _ = limiter.reserve("assets", now)
+	assert.Equal(t, 100*time.Millisecond, wait)
+	wait, _ = limiter.reserve("data-bulk", now.Add(time.Second))
+	assert.Zero(t, wait)
+	wait, _ = limiter.reserve("data-bulk", now.Add(time.Second))
+	assert.Equal(t, time.Second, wait)
+
+	stats := limiter.Stats()
+	assert.Equal(t, int64(5), stats.Requests)
+	assert.Equal(t, int64(2), stats.Delayed)
+	assert.Equal(t, time.Second, stats.MaxWait)
+}
+
+func TestRateLimiterReject(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(RateLimitConfig{PerOperation: map[string]RateLimit{"data": {Rate: 0.001, Burst: 1}}, Reject: true})
+	httpClient := &http.Client{Transport: limiter.transport(nil, "/v2")}
+
+	_, err := httpClient.Get(server.URL + "/v2/data?assetId=1")
+	assert.NoError(t, err)
+	_, err = httpClient.Get(server.URL + "/v2/data?assetId=2")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	_, err = httpClient.Get(server.URL + "/v2/assets")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), limiter.Stats().Rejected)
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"message": "asset already exists"}`))
+	}))
+	defer server.Close()
+
+	c := New(Config{Endpoint: server.URL, ApiKey: "key"})
+	_, res, err := c.AssetsAPI.GetAssetById(c.AuthenticationContext(context.Background()), 1).Execute()
+	err = NewAPIError("GetAssetById", res, err)
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	assert.Equal(t, "GetAssetById", apiErr.Operation)
+	assert.JSONEq(t, `{"message": "asset already exists"}`, string(apiErr.ResponseBody))
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.Contains(t, err.Error(), "asset already exists")
+
+	assert.ErrorIs(t, &APIError{StatusCode: http.StatusUnprocessableEntity}, ErrValidation)
+	assert.ErrorIs(t, &APIError{StatusCode: http.StatusBadGateway}, ErrServer)
+	assert.ErrorIs(t, &APIError{StatusCode: http.StatusUnauthorized}, ErrUnauthorized)
+	assert.NoError(t, NewAPIError("GetAssetById", nil, nil))
+}
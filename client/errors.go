@@ -0,0 +1,94 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v3"
+)
+
+// Sentinel errors matching the HTTP status of an *APIError. Use them with errors.Is.
+var (
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError is returned by the helpers of this module if a call to the Eliona API fails. StatusCode is
+// 0 if no response was received, e.g. because the connection was refused.
+type APIError struct {
+	Operation    string
+	StatusCode   int
+	ResponseBody []byte
+	Err          error
+}
+
+func (e *APIError) Error() string {
+	body := strings.TrimSpace(string(e.ResponseBody))
+	if body == "" {
+		return fmt.Sprintf("%s: %v", e.Operation, e.Err)
+	}
+	return fmt.Sprintf("%s: %v: %s", e.Operation, e.Err, body)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the status code of the error matches the target sentinel error.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// NewAPIError wraps the error returned by an API call of the given operation into an *APIError. The
+// response and the error are the values returned by Execute. NewAPIError returns nil if err is nil.
+func NewAPIError(operation string, response *http.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+	apiErr := &APIError{Operation: operation, Err: err}
+	if response != nil {
+		apiErr.StatusCode = response.StatusCode
+	}
+	var openAPIErr *api.GenericOpenAPIError
+	if errors.As(err, &openAPIErr) {
+		apiErr.ResponseBody = openAPIErr.Body()
+	}
+	return apiErr
+}
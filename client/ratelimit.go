@@ -0,0 +1,190 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned instead of sending a request if the rate limit is exceeded and the
+// limiter is configured to reject instead of wait.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimit allows Rate requests per second on average and bursts of up to Burst requests.
+// A zero Rate means unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig configures a RateLimiter. Global limits all requests, PerOperation limits the
+// requests to single operations of the API, identified by the first path segment after the API
+// endpoint, e.g. "data", "data-bulk" or "assets". If Reject is set, requests exceeding a limit fail
+// with ErrRateLimited. Otherwise, they wait until they are allowed or their context is done.
+type RateLimitConfig struct {
+	Global       RateLimit
+	PerOperation map[string]RateLimit
+	Reject       bool
+}
+
+// RateLimiterStats are the metrics of a RateLimiter since its creation.
+type RateLimiterStats struct {
+	Requests  int64
+	Delayed   int64
+	Rejected  int64
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// RateLimiter limits the requests to the Eliona API with token buckets. Set it in Config.RateLimiter.
+// One limiter can be shared by several clients to limit them together. The zero value does not limit
+// requests but still counts them; use NewRateLimiter to set limits.
+type RateLimiter struct {
+	reject    bool
+	mutex     sync.Mutex
+	global    *tokenBucket
+	operation map[string]*tokenBucket
+	stats     RateLimiterStats
+}
+
+// NewRateLimiter creates a rate limiter with the given limits.
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	now := time.Now()
+	limiter := &RateLimiter{
+		reject:    config.Reject,
+		global:    newTokenBucket(config.Global, now),
+		operation: make(map[string]*tokenBucket),
+	}
+	for operation, limit := range config.PerOperation {
+		limiter.operation[operation] = newTokenBucket(limit, now)
+	}
+	return limiter
+}
+
+// Stats returns the current metrics of the limiter.
+func (l *RateLimiter) Stats() RateLimiterStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.stats
+}
+
+// reserve takes a token for the operation and returns how long the caller has to wait before sending
+// the request. If the limiter rejects, no token is taken and ErrRateLimited is returned instead.
+func (l *RateLimiter) reserve(operation string, now time.Time) (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.stats.Requests++
+
+	var buckets []*tokenBucket
+	if l.global != nil {
+		buckets = append(buckets, l.global)
+	}
+	if bucket, ok := l.operation[operation]; ok {
+		buckets = append(buckets, bucket)
+	}
+	var wait time.Duration
+	for _, bucket := range buckets {
+		wait = max(wait, bucket.take(now))
+	}
+	if wait <= 0 {
+		return 0, nil
+	}
+	if l.reject {
+		for _, bucket := range buckets {
+			bucket.giveBack()
+		}
+		l.stats.Rejected++
+		return 0, fmt.Errorf("%w for %s", ErrRateLimited, operation)
+	}
+	l.stats.Delayed++
+	l.stats.TotalWait += wait
+	l.stats.MaxWait = max(l.stats.MaxWait, wait)
+	return wait, nil
+}
+
+// transport wraps the transport with the limiter. basePath is the path of the API endpoint, which is
+// removed from request paths to determine the operation.
+func (l *RateLimiter) transport(next http.RoundTripper, basePath string) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &rateLimitTransport{next: next, limiter: l, basePath: strings.TrimSuffix(basePath, "/")}
+}
+
+type rateLimitTransport struct {
+	next     http.RoundTripper
+	limiter  *RateLimiter
+	basePath string
+}
+
+func (t *rateLimitTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	operation := strings.TrimPrefix(strings.TrimPrefix(request.URL.Path, t.basePath), "/")
+	operation, _, _ = strings.Cut(operation, "/")
+
+	wait, err := t.limiter.reserve(operation, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		case <-timer.C:
+		}
+	}
+	return t.next.RoundTrip(request)
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	burst := float64(max(limit.Burst, 1))
+	return &tokenBucket{rate: limit.Rate, burst: burst, tokens: burst, last: now}
+}
+
+// take removes a token and returns the time until this token is available.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) giveBack() {
+	if b.rate > 0 {
+		b.tokens++
+	}
+}
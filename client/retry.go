@@ -0,0 +1,159 @@
+//  This file is part of the eliona project.
+//  Copyright © 2025 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package client
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// RetryPolicy defines how transient failures of the Eliona API are retried. Only idempotent GET and
+// PUT requests are retried, on connection errors and on the status codes 502, 503 and 504. The delay
+// before retry n is InitialDelay * 2^(n-1) plus random jitter of up to a half of that, capped at
+// MaxDelay. A Retry-After header of the response takes precedence, but is capped at MaxDelay as well.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// DefaultRetryPolicy returns a policy trying each request up to five times over about 15 seconds.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  5,
+		InitialDelay: 500 * time.Millisecond,
+		MaxDelay:     8 * time.Second,
+	}
+}
+
+// RetryTransport wraps the transport with the retry policy. If transport is nil,
+// http.DefaultTransport is used. Set the result as Config.Transport to enable retries.
+func RetryTransport(transport http.RoundTripper, policy RetryPolicy) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &retryTransport{next: transport, policy: policy}
+}
+
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != http.MethodGet && request.Method != http.MethodPut {
+		return t.next.RoundTrip(request)
+	}
+	if request.Body != nil && request.Body != http.NoBody && request.GetBody == nil {
+		// The body cannot be sent again.
+		return t.next.RoundTrip(request)
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptRequest := request
+		if attempt > 1 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptRequest = request.Clone(request.Context())
+			attemptRequest.Body = body
+		}
+
+		response, err := t.next.RoundTrip(attemptRequest)
+		if attempt >= t.policy.MaxAttempts || !retryable(response, err) {
+			return response, err
+		}
+
+		delay := t.delay(attempt, response)
+		if response != nil {
+			// Drain the body so the connection can be reused.
+			_, _ = io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+			log.Debug("client", "Retrying %s %s in %v after status %d", request.Method, request.URL.Path, delay, response.StatusCode)
+		} else {
+			log.Debug("client", "Retrying %s %s in %v after error: %v", request.Method, request.URL.Path, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *retryTransport) delay(attempt int, response *http.Response) time.Duration {
+	if response != nil {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			if t.policy.MaxDelay > 0 {
+				retryAfter = min(retryAfter, t.policy.MaxDelay)
+			}
+			return retryAfter
+		}
+	}
+	delay := t.policy.InitialDelay << (attempt - 1)
+	if delay <= 0 || (t.policy.MaxDelay > 0 && delay > t.policy.MaxDelay) {
+		delay = t.policy.MaxDelay
+	}
+	if delay > 1 {
+		delay += rand.N(delay / 2)
+	}
+	if t.policy.MaxDelay > 0 {
+		delay = min(delay, t.policy.MaxDelay)
+	}
+	return delay
+}
+
+func retryable(response *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, io.EOF) ||
+			(errors.As(err, &netErr) && netErr.Timeout())
+	}
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses the value of a Retry-After header, given in seconds or as HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
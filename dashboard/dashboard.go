@@ -16,6 +16,7 @@
 package dashboard
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 
@@ -28,40 +29,66 @@ import (
 
 // UpsertWidgetType insert or updates an asset and returns the id
 func UpsertWidgetType(apiEndpoint string, apiKey string, widgetType api.WidgetType) error {
-	_, _, err := client.NewClient(apiEndpoint).WidgetsTypesAPI.
-		PutWidgetType(client.AuthenticationContext(apiKey)).
+	return UpsertWidgetTypeContext(context.Background(), apiEndpoint, apiKey, widgetType)
+}
+
+// UpsertWidgetTypeContext works like UpsertWidgetType using the given context.
+func UpsertWidgetTypeContext(ctx context.Context, apiEndpoint string, apiKey string, widgetType api.WidgetType) error {
+	_, res, err := client.NewClientContext(ctx, apiEndpoint).WidgetsTypesAPI.
+		PutWidgetType(client.AuthenticationContextWrap(ctx, apiKey)).
 		Expansions([]string{"WidgetType.elements"}).
 		WidgetType(widgetType).
 		Execute()
+	err = client.NewAPIError("PutWidgetType", res, err)
 	if err != nil {
 		tools.LogError(fmt.Errorf("Upserting widget type %v: %w", widgetType.Name, err))
 	}
 	return err
 }
 
-func initWidgetTypeFile(apiEndpoint string, apiKey string, path string) error {
+// planRecorder is implemented by the connection passed to init and patch functions during a dry run
+// (see app.DryRunInit). Instead of changing things, the functions record what they would do.
+type planRecorder interface {
+	RecordPlan(kind string, description string)
+}
+
+func initWidgetTypeFile(ctx context.Context, apiEndpoint string, apiKey string, connection db.Connection, path string) error {
 	widgetType, err := common.UnmarshalFile[api.WidgetType](path)
 	if err != nil {
 		return fmt.Errorf("unmarshalling file %s: %v", path, err)
 	}
-	return UpsertWidgetType(apiEndpoint, apiKey, widgetType)
+	if recorder, ok := connection.(planRecorder); ok {
+		recorder.RecordPlan("widget type", fmt.Sprintf("%s from %s", widgetType.Name, path))
+		return nil
+	}
+	return UpsertWidgetTypeContext(ctx, apiEndpoint, apiKey, widgetType)
 }
 
 // InitWidgetTypeFile inserts or updates the type build from the content of the given file.
 func InitWidgetTypeFile(apiEndpoint string, apiKey string, path string) func(db.Connection) error {
-	return func(db.Connection) error {
-		return initWidgetTypeFile(apiEndpoint, apiKey, path)
+	return InitWidgetTypeFileContext(context.Background(), apiEndpoint, apiKey, path)
+}
+
+// InitWidgetTypeFileContext works like InitWidgetTypeFile. The returned function uses the given context.
+func InitWidgetTypeFileContext(ctx context.Context, apiEndpoint string, apiKey string, path string) func(db.Connection) error {
+	return func(connection db.Connection) error {
+		return initWidgetTypeFile(ctx, apiEndpoint, apiKey, connection, path)
 	}
 }
 
 func InitWidgetTypeFiles(apiEndpoint string, apiKey string, pattern string) func(db.Connection) error {
-	return func(db.Connection) error {
+	return InitWidgetTypeFilesContext(context.Background(), apiEndpoint, apiKey, pattern)
+}
+
+// InitWidgetTypeFilesContext works like InitWidgetTypeFiles. The returned function uses the given context.
+func InitWidgetTypeFilesContext(ctx context.Context, apiEndpoint string, apiKey string, pattern string) func(db.Connection) error {
+	return func(connection db.Connection) error {
 		paths, err := filepath.Glob(pattern)
 		if err != nil {
 			return fmt.Errorf("glob file pattern %s: %v", pattern, err)
 		}
 		for _, path := range paths {
-			err := initWidgetTypeFile(apiEndpoint, apiKey, path)
+			err := initWidgetTypeFile(ctx, apiEndpoint, apiKey, connection, path)
 			if err != nil {
 				return fmt.Errorf("initializing widget type %s: %v", path, err)
 			}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -12,70 +14,40 @@ type FieldTag struct {
 	ParamName  string
 	SubType    asset.SubType
 	Filterable bool
+	OmitEmpty  bool
 }
 
+// parseElionaTag parses the eliona tag of the field with asset.ParseTag. Fields without eliona tag
+// result in an empty FieldTag.
 func parseElionaTag(field reflect.StructField) (*FieldTag, error) {
-	elionaTag := field.Tag.Get("eliona")
-	subtypeTag := field.Tag.Get("subtype")
-
-	elionaTagParts := strings.Split(elionaTag, ",")
-	if len(elionaTagParts) < 1 {
-		return nil, fmt.Errorf("invalid eliona tag on field %s", field.Name)
-	}
-
-	paramName := elionaTagParts[0]
-	filterable := len(elionaTagParts) > 1 && elionaTagParts[1] == "filterable"
-
-	var subType asset.SubType
-	if subtypeTag != "" {
-		subType = asset.SubType(subtypeTag)
-		switch subType {
-		case asset.Status, asset.Info, asset.Input, asset.Output, asset.Property:
-			// valid subtype
-		default:
-			return nil, fmt.Errorf("invalid subtype in eliona tag on field %s", field.Name)
-		}
+	tag, _, err := asset.ParseTag(field)
+	if err != nil {
+		return nil, err
 	}
-
 	return &FieldTag{
-		ParamName:  paramName,
-		SubType:    subType,
-		Filterable: filterable,
+		ParamName:  tag.AttributeName,
+		SubType:    asset.SubType(tag.Subtype),
+		Filterable: tag.Filterable,
+		OmitEmpty:  tag.OmitEmpty,
 	}, nil
 }
 
-// StructToMap converts a struct to map of struct properties
+// StructToMap converts a struct to map of struct properties. Embedded and nested structs are handled as
+// described for asset.WalkTaggedFields; nested structs without prefix are written as JSON object.
 func StructToMap(input any) (map[string]string, error) {
 	if input == nil {
 		return nil, fmt.Errorf("input is nil")
 	}
 
-	inputValue := reflect.ValueOf(input)
-	inputType := reflect.TypeOf(input)
-
-	if inputValue.Kind() == reflect.Ptr {
-		inputValue = inputValue.Elem()
-		inputType = inputType.Elem()
-	}
-
-	if inputValue.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("input is not a struct")
-	}
-
 	output := make(map[string]string)
-	for i := 0; i < inputValue.NumField(); i++ {
-		fieldType := inputType.Field(i)
-
-		fieldTag, err := parseElionaTag(fieldType)
-		if err != nil {
-			return nil, err
-		}
-
+	var marshalErrs []error
+	err := asset.WalkTaggedFields(input, func(fieldTag asset.ElionaTag, fieldValue reflect.Value) {
 		if !fieldTag.Filterable {
-			continue
+			return
+		}
+		if fieldTag.OmitEmpty && fieldValue.IsZero() {
+			return
 		}
-
-		fieldValue := inputValue.Field(i)
 
 		var strValue string
 		switch fieldValue.Kind() {
@@ -92,11 +64,25 @@ func StructToMap(input any) (map[string]string, error) {
 			} else {
 				strValue = fmt.Sprintf("%v", fieldValue.Interface())
 			}
+		case reflect.Struct, reflect.Map:
+			if _, ok := fieldValue.Interface().(fmt.Stringer); ok {
+				strValue = fmt.Sprintf("%v", fieldValue.Interface())
+				break
+			}
+			bytes, err := json.Marshal(fieldValue.Interface())
+			if err != nil {
+				marshalErrs = append(marshalErrs, fmt.Errorf("marshalling field %s: %w", fieldTag.AttributeName, err))
+				return
+			}
+			strValue = string(bytes)
 		default:
 			strValue = fmt.Sprintf("%v", fieldValue.Interface())
 		}
 
-		output[fieldTag.ParamName] = strValue
+		output[fieldTag.AttributeName] = strValue
+	})
+	if err = errors.Join(append([]error{err}, marshalErrs...)...); err != nil {
+		return nil, err
 	}
 
 	return output, nil
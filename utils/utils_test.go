@@ -58,3 +58,44 @@ func TestStructToMap(t *testing.T) {
 	assert.Equal(t, "test", output["name"])
 	assert.Equal(t, "xyz", output["firm_ware"])
 }
+
+func TestStructToMapOmitEmpty(t *testing.T) {
+	type device struct {
+		Name   string `eliona:"name,filterable,omitempty"`
+		Serial string `eliona:"serial,filterable,omitempty"`
+	}
+	output, err := StructToMap(device{Name: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "test"}, output)
+
+	type invalid struct {
+		Level int `eliona:"level" subtype:"data"`
+	}
+	_, err = StructToMap(invalid{})
+	assert.ErrorIs(t, err, asset.ErrInvalidTag)
+}
+
+func TestStructToMapNested(t *testing.T) {
+	type location struct {
+		Building string `json:"building" eliona:"building,filterable"`
+		Room     string `json:"room" eliona:"room,filterable"`
+	}
+	type device struct {
+		testDeviceInfo
+		Location  location  `eliona:",prefix=location_"`
+		Position  *location `eliona:"position,filterable"`
+		Secondary *location `eliona:",prefix=secondary_"`
+	}
+	output, err := StructToMap(&device{
+		testDeviceInfo: testDeviceInfo{Name: "test"},
+		Location:       location{Building: "HQ", Room: "1.01"},
+		Position:       &location{Building: "HQ"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "test", output["name"])
+	assert.Equal(t, "HQ", output["location_building"])
+	assert.Equal(t, "1.01", output["location_room"])
+	assert.JSONEq(t, `{"building": "HQ", "room": ""}`, output["position"])
+	assert.NotContains(t, output, "secondary_building")
+	assert.Equal(t, 9, len(output))
+}